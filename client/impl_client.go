@@ -0,0 +1,294 @@
+package client
+
+import (
+	"context"
+	"math"
+	"pandora/pod"
+	"pandora/store"
+	"sort"
+	"strings"
+	"time"
+
+	ver "github.com/hashicorp/go-version"
+)
+
+// ** Config Impl **
+
+// 按priority从高到低排列仓库，priority相同时保持原有顺序；names为空时返回全部已配置的仓库
+func (s *Config) OrderedRepos(names []string) []string {
+	if len(names) == 0 {
+		names = make([]string, 0, len(s.Repos))
+		for _, repo := range s.Repos {
+			names = append(names, repo.Name)
+		}
+	}
+	res := make([]string, len(names))
+	copy(res, names)
+	sort.SliceStable(res, func(i, j int) bool {
+		return s.RepoPriority(res[i]) > s.RepoPriority(res[j])
+	})
+	return res
+}
+
+func (s *Config) RepoPriority(name string) int {
+	for _, repo := range s.Repos {
+		if repo.Name == name {
+			return repo.Priority
+		}
+	}
+	return math.MinInt32
+}
+
+// ** Client Impl **
+
+// 使用指定存储创建客户端
+func New(conf *Config, aStore store.Store) *Client {
+	if conf == nil {
+		conf = new(Config)
+	}
+	return &Client{store: aStore, conf: conf}
+}
+
+// 打开dbPath指定的SQLite索引数据库，不存在时创建
+func Open(conf *Config, dbPath string) (*Client, error) {
+	aStore, e := store.OpenSQLite(dbPath)
+	if e != nil {
+		return nil, e
+	}
+	return New(conf, aStore), nil
+}
+
+func (s *Client) Close() error {
+	return s.store.Close()
+}
+
+// 底层存储，供同步写入使用
+func (s *Client) Store() store.Store {
+	return s.store
+}
+
+func (s *Client) OrderedRepos(names []string) []string {
+	return s.conf.OrderedRepos(names)
+}
+
+// 查询模块某版本的依赖，repos不为空时仅查询指定仓库；多个仓库存在同一版本时按repos的顺序(未指定时按仓库优先级)使用spec
+func (s *Client) Depends(ctx context.Context, module string, version string, repos ...string) (*DependsResult, error) {
+	if module == "" || version == "" {
+		return nil, ErrInvalidArgs
+	}
+	baseModule := pod.BaseModule(module)
+	specs, e := s.Specs(ctx, baseModule, version, repos...)
+	if e != nil {
+		return nil, e
+	}
+	s.checkSpecConflict(baseModule, version, specs)
+	res := &DependsResult{Module: module, Version: version, Repos: make([]string, 0, len(specs))}
+	var spec *pod.Spec
+	for _, aSpec := range specs {
+		res.Repos = append(res.Repos, aSpec.Repo)
+		if spec == nil && aSpec.JSON != "" {
+			spec, _ = pod.NewSpecWithJSONString(aSpec.JSON)
+		}
+	}
+	if spec == nil {
+		return res, nil
+	}
+	deps := spec.GetAllDepends(module)
+	l := len(deps)
+	if l == 0 {
+		return res, nil
+	}
+	cap := l/2 + 1
+	resHead := make([]*pod.DependBase, 0, cap)
+	resFoot := make([]*pod.DependBase, 0, cap)
+	baseModule += "/"
+	for key, val := range deps {
+		aDep := pod.DependBase{N: key, V: val}
+		if strings.HasPrefix(key, baseModule) {
+			resHead = append(resHead, &aDep)
+		} else {
+			resFoot = append(resFoot, &aDep)
+		}
+	}
+	res.Depends = append(resHead, resFoot...)
+	return res, nil
+}
+
+// 查询满足版本要求的最新版本，没有满足要求的版本时返回空字符串
+func (s *Client) NewestVersion(ctx context.Context, module string, constraint string, repos ...string) (string, error) {
+	versions, e := s.Versions(ctx, module, constraint, repos...)
+	if e != nil {
+		return "", e
+	}
+	return pod.MaxVersion("", versions...)
+}
+
+// 查询满足版本要求的全部版本，repos不为空时仅返回指定仓库中的版本；没有spec的版本依赖未知，不返回
+func (s *Client) Versions(ctx context.Context, module string, constraint string, repos ...string) ([]string, error) {
+	var aConstraint ver.Constraints
+	if len(constraint) > 0 {
+		aConstraint, _ = ver.NewConstraint(constraint)
+	}
+	records, e := s.store.ListVersions(ctx, pod.BaseModule(module))
+	if e != nil {
+		return nil, e
+	}
+	m := make(map[string]bool)
+	for _, r := range records {
+		if len(repos) > 0 && repoIndex(repos, r.Repo) < 0 {
+			continue
+		}
+		if aConstraint != nil {
+			aVer, e := ver.NewVersion(r.Version)
+			if e != nil || !aConstraint.Check(aVer) {
+				continue
+			}
+		}
+		m[r.Version] = true
+	}
+	res := make([]string, 0, len(m))
+	for v := range m {
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// 将版本要求解析为具体版本，version为空时取最新版本
+func (s *Client) ResolveVersion(ctx context.Context, module string, version string, repos ...string) (string, error) {
+	if pod.IsVersion(version) {
+		return version, nil
+	}
+	return s.NewestVersion(ctx, module, version, repos...)
+}
+
+// 查询模块某版本在各仓库中的spec，repos不为空时按repos的顺序排序，否则按仓库优先级排序
+func (s *Client) Specs(ctx context.Context, module string, version string, repos ...string) ([]*RepoSpec, error) {
+	records, e := s.store.GetSpecs(ctx, pod.BaseModule(module), version)
+	if e != nil {
+		return nil, e
+	}
+	specs := make([]*RepoSpec, 0, len(records))
+	for _, r := range records {
+		if len(repos) > 0 && repoIndex(repos, r.Repo) < 0 {
+			continue
+		}
+		specs = append(specs, &RepoSpec{Repo: r.Repo, JSON: r.SpecJSON})
+	}
+	// 指定repos时按其顺序(例如Podfile中source的声明顺序)，否则按priority
+	ordered := repos
+	if len(ordered) == 0 {
+		ordered = s.conf.OrderedRepos(nil)
+	}
+	sort.SliceStable(specs, func(i, j int) bool {
+		return repoOrder(ordered, specs[i].Repo) < repoOrder(ordered, specs[j].Repo)
+	})
+	return specs, nil
+}
+
+// 查询模块某版本优先级最高的spec，不存在时返回ErrNotFound
+func (s *Client) Spec(ctx context.Context, module string, version string, repos ...string) (*RepoSpec, error) {
+	specs, e := s.Specs(ctx, module, version, repos...)
+	if e != nil {
+		return nil, e
+	}
+	for _, aSpec := range specs {
+		if aSpec.JSON != "" {
+			return aSpec, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// 按名称模糊查询模块
+func (s *Client) Modules(ctx context.Context, keyword string, limit int) ([]string, error) {
+	if limit < 1 {
+		limit = 50
+	}
+	return s.store.ListModules(ctx, keyword, limit)
+}
+
+// 查询依赖module的模块版本，version不为空时仅返回版本要求与之匹配的依赖方
+func (s *Client) Dependents(ctx context.Context, module string, version string) ([]*pod.DependBase, error) {
+	baseModule := pod.BaseModule(module)
+	records, e := s.store.SearchSpecs(ctx, "\""+baseModule)
+	if e != nil {
+		return nil, e
+	}
+	dup := make(map[string]bool)
+	res := make([]*pod.DependBase, 0, 10)
+	for _, r := range records {
+		m, v := r.Module, r.Version
+		if r.SpecJSON == "" || m == baseModule {
+			continue
+		}
+		spec, e := pod.NewSpecWithJSONString(r.SpecJSON)
+		if e != nil {
+			continue
+		}
+		for _, aDep := range spec.AllDepends() {
+			if pod.BaseModule(aDep.N) != baseModule {
+				continue
+			}
+			if version != "" && aDep.V != "" && !pod.MatchVersionConstraint(aDep.V, version) {
+				continue
+			}
+			if key := m + "@" + v; !dup[key] {
+				dup[key] = true
+				res = append(res, &pod.DependBase{N: m, V: v})
+			}
+			break
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].N != res[j].N {
+			return res[i].N < res[j].N
+		}
+		return pod.CompareVersion(res[i].V, res[j].V) > 0
+	})
+	return res, nil
+}
+
+// 最后一次同步的时间，从未同步时返回零值
+func (s *Client) LastSync(ctx context.Context) (time.Time, error) {
+	return s.store.LastSync(ctx)
+}
+
+// 解析失败或CDN仓库中未下载spec的版本JSON为空，不参与比较
+func (s *Client) checkSpecConflict(module string, version string, specs []*RepoSpec) {
+	if s.OnSpecConflict == nil {
+		return
+	}
+	valid := make([]*RepoSpec, 0, len(specs))
+	for _, aSpec := range specs {
+		if aSpec.JSON != "" {
+			valid = append(valid, aSpec)
+		}
+	}
+	if len(valid) < 2 {
+		return
+	}
+	specs = valid
+	for _, aSpec := range specs[1:] {
+		if aSpec.JSON != specs[0].JSON {
+			s.OnSpecConflict(&SpecConflict{Module: module, Version: version, Specs: specs})
+			return
+		}
+	}
+}
+
+// 仓库在排序列表中的位置，不在列表中的仓库排在最后
+func repoOrder(ordered []string, repo string) int {
+	if idx := repoIndex(ordered, repo); idx > -1 {
+		return idx
+	}
+	return len(ordered)
+}
+
+func repoIndex(repos []string, repo string) int {
+	for idx, r := range repos {
+		if r == repo {
+			return idx
+		}
+	}
+	return -1
+}
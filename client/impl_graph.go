@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"pandora/pod"
+)
+
+// 构建依赖的图节点：解析当前版本、最新版本、upPodfile中指定的升级版本及对应版本的依赖
+// :git等外部来源的模块保持Podfile中的指定，不参与升级
+func (s *Client) GraphModule(ctx context.Context, aDepend pod.IDepend, upPodfile *pod.Podfile, repos []string) (*pod.GraphModule, error) {
+	if source := aDepend.ExternalSource(); source != nil && !aDepend.IsLocal() {
+		return s.externalGraphModule(ctx, aDepend, source, repos)
+	}
+	graphModule := new(pod.GraphModule)
+	var e error
+	if upPodfile != nil {
+		if upVersion, exist := upPodfile.GetDependVersion(nil, aDepend.Name()); exist {
+			if graphModule.UpdateToVersion, e = s.ResolveVersion(ctx, aDepend.Name(), upVersion, repos...); e != nil {
+				return nil, e
+			}
+		}
+	}
+	if graphModule.NewestVersion, e = s.NewestVersion(ctx, aDepend.Name(), "", repos...); e != nil {
+		return nil, e
+	}
+	if pod.IsVersion(aDepend.Version()) {
+		graphModule.Version = aDepend.Version()
+	} else if aDepend.Version() == "" {
+		graphModule.Version = graphModule.NewestVersion
+	} else if graphModule.Version, e = s.NewestVersion(ctx, aDepend.Name(), aDepend.Version(), repos...); e != nil {
+		return nil, e
+	}
+	graphModule.Name = aDepend.Name()
+	graphModule.IsLocal = aDepend.IsLocal()
+	graphModule.Source = aDepend.ExternalSource()
+	if aDepend.Subdepends() != nil && graphModule.UseVersion() == graphModule.Version {
+		graphModule.Depends = aDepend.Subdepends()
+	} else if graphModule.Version != "" {
+		res, e := s.Depends(ctx, graphModule.Name, graphModule.UseVersion(), repos...)
+		if e != nil {
+			return nil, e
+		}
+		if len(res.Depends) > 0 {
+			graphModule.Depends = res.Depends
+		}
+	}
+	return graphModule, nil
+}
+
+// tag为版本号时从索引读取外部来源模块的依赖
+func (s *Client) externalGraphModule(ctx context.Context, aDepend pod.IDepend, source *pod.DependSource, repos []string) (*pod.GraphModule, error) {
+	graphModule := new(pod.GraphModule)
+	graphModule.Name = aDepend.Name()
+	graphModule.Source = source
+	if pod.IsVersion(source.Tag) {
+		graphModule.Version = source.Tag
+	} else if pod.IsVersion(aDepend.Version()) {
+		graphModule.Version = aDepend.Version()
+	}
+	if graphModule.Version != "" {
+		res, e := s.Depends(ctx, graphModule.Name, graphModule.Version, repos...)
+		if e != nil {
+			return nil, e
+		}
+		if len(res.Depends) > 0 {
+			graphModule.Depends = res.Depends
+		}
+	}
+	return graphModule, nil
+}
+
+// 从索引构建模块某版本的传递依赖图，依赖使用满足版本要求的最新版本；maxDepth大于0时仅查询该深度以内的依赖
+func (s *Client) Closure(ctx context.Context, module string, version string, maxDepth int) (pod.GraphPodfile, error) {
+	graphPodfile := make(pod.GraphPodfile)
+	root := &pod.GraphModule{Name: module, Version: version}
+	res, e := s.Depends(ctx, module, version)
+	if e != nil {
+		return nil, e
+	}
+	root.Depends = res.Depends
+	graphPodfile[module] = root
+	type item struct {
+		module *pod.GraphModule
+		depth  int
+	}
+	queue := []*item{{root, 0}}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if maxDepth > 0 && current.depth >= maxDepth {
+			continue
+		}
+		for _, aDep := range current.module.Depends {
+			if _, ok := graphPodfile[aDep.N]; ok {
+				continue
+			}
+			aModule := &pod.GraphModule{Name: aDep.N}
+			if aModule.Version, e = s.NewestVersion(ctx, aDep.N, aDep.V); e != nil {
+				return nil, e
+			}
+			if aModule.Version != "" {
+				if res, e = s.Depends(ctx, aDep.N, aModule.Version); e != nil {
+					return nil, e
+				}
+				aModule.Depends = res.Depends
+			}
+			graphPodfile[aDep.N] = aModule
+			queue = append(queue, &item{aModule, current.depth + 1})
+		}
+	}
+	return graphPodfile, nil
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"errors"
+	"pandora/pod"
+	"pandora/store"
+)
+
+var (
+	ErrInvalidArgs = errors.New("模块名和版本号不能为空！")
+	ErrNotFound    = errors.New("未找到对应的模块版本！")
+)
+
+// 客户端配置，仓库顺序及优先级决定多个仓库存在同一版本时使用哪个spec
+type Config struct {
+	Repos []*Repo
+}
+
+type Repo struct {
+	Name     string
+	Priority int
+}
+
+// 查询pandora索引的客户端，并发安全
+type Client struct {
+	store store.Store
+	conf  *Config
+
+	// 同一模块版本在多个仓库中的spec不同时回调，可为nil
+	OnSpecConflict func(c *SpecConflict)
+}
+
+// 模块版本在某个仓库中的spec
+type RepoSpec struct {
+	Repo string
+	JSON string
+}
+
+type SpecConflict struct {
+	Module  string
+	Version string
+	// 按仓库优先级排序，第一个为实际使用的spec
+	Specs []*RepoSpec
+}
+
+type DependsResult struct {
+	Module  string
+	Version string
+	Repos   []string
+	Depends []*pod.DependBase
+}
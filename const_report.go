@@ -0,0 +1,342 @@
+package main
+
+// ** HTML Report **
+// 报告为单个离线HTML文件，样式与脚本全部内联，数据以JSON形式嵌入
+const _HTML_REPORT_TEMPLATE = `<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>Pandora 升级报告 {{.Time}}</title>
+<style>
+body { margin: 0; font: 13px/1.5 -apple-system, "Helvetica Neue", Helvetica, Arial, sans-serif; color: #222; background: #f6f7f9; }
+header { padding: 16px 24px; background: #263238; color: #fff; }
+header h1 { margin: 0; font-size: 20px; }
+header .meta { opacity: .7; }
+nav { display: flex; flex-wrap: wrap; padding: 0 24px; background: #fff; border-bottom: 1px solid #ddd; }
+nav a { padding: 10px 14px; cursor: pointer; color: #555; border-bottom: 2px solid transparent; }
+nav a.active { color: #1565c0; border-bottom-color: #1565c0; }
+main { padding: 16px 24px; }
+.bar { display: flex; gap: 12px; align-items: center; margin-bottom: 12px; flex-wrap: wrap; }
+.bar input[type=search] { padding: 4px 8px; width: 240px; }
+table { border-collapse: collapse; width: 100%; background: #fff; }
+th, td { padding: 4px 8px; border-bottom: 1px solid #eee; text-align: left; white-space: nowrap; }
+th { cursor: pointer; user-select: none; background: #fafafa; position: sticky; top: 0; }
+th.asc:after { content: " ▲"; } th.desc:after { content: " ▼"; }
+td.deps { white-space: normal; color: #666; }
+tr.differ td { background: #fff8e1; }
+.tag { display: inline-block; padding: 0 6px; margin-right: 4px; border-radius: 3px; font-size: 11px; }
+.tag.common { background: #dbeafe; } .tag.implicit { background: #fff9c4; } .tag.local { background: #eee; }
+.tag.external { background: #e1bee7; } .tag.up { background: #c8e6c9; } .tag.down { background: #ffcdd2; }
+.graph { margin-top: 16px; background: #fff; border: 1px solid #ddd; }
+.graph svg { width: 100%; height: 600px; cursor: grab; }
+.graph line { stroke: #bbb; } .graph line.hl { stroke: #1565c0; stroke-width: 2; }
+.graph circle { stroke: #888; stroke-width: 1; cursor: pointer; }
+.graph circle.up { stroke: #2e7d32; stroke-width: 3; } .graph circle.down { stroke: #c62828; stroke-width: 3; }
+.graph text { font-size: 10px; pointer-events: none; }
+.graph .dim { opacity: .15; }
+.summary { margin-bottom: 12px; }
+.summary span { margin-right: 16px; }
+</style>
+</head>
+<body>
+<header><h1>Pandora 升级报告</h1><div class="meta">{{.Time}} · {{len .Podfiles}} 个Podfile · {{.Output}}</div></header>
+<nav id="tabs"></nav>
+<main id="content"></main>
+<script>var REPORT = {{.Podfiles}};</script>
+<script>
+(function () {
+  var COLORS = { local: "#eeeeee", implicit: "#fff9c4", common: "#dbeafe", external: "#e1bee7", normal: "#ffffff" };
+  var tabs = document.getElementById("tabs");
+  var content = document.getElementById("content");
+
+  function el(tag, attrs, text) {
+    var e = document.createElement(tag);
+    for (var k in attrs || {}) e.setAttribute(k, attrs[k]);
+    if (text !== undefined) e.textContent = text;
+    return e;
+  }
+  function svg(tag, attrs) {
+    var e = document.createElementNS("http://www.w3.org/2000/svg", tag);
+    for (var k in attrs || {}) e.setAttribute(k, attrs[k]);
+    return e;
+  }
+  function kind(m) {
+    if (m.is_local) return "local";
+    if (m.external) return "external";
+    if (m.is_implicit) return "implicit";
+    if (m.is_common) return "common";
+    return "normal";
+  }
+  function tags(m) {
+    var res = [];
+    if (m.is_common) res.push("common");
+    if (m.is_implicit) res.push("implicit");
+    if (m.is_local) res.push("local");
+    if (m.external) res.push("external");
+    if (m.upgrade_tag === "up" || m.upgrade_tag === "down") res.push(m.upgrade_tag);
+    return res;
+  }
+  function baseName(p) { var s = p.split("/"); return s.slice(-3).join("/"); }
+  function cmpVersion(a, b) {
+    var x = String(a || "").split(/[.\-]/), y = String(b || "").split(/[.\-]/);
+    for (var i = 0; i < Math.max(x.length, y.length); i++) {
+      var p = parseInt(x[i], 10), q = parseInt(y[i], 10);
+      if (isNaN(p) || isNaN(q)) { var c = String(x[i] || "").localeCompare(String(y[i] || "")); if (c) return c; continue; }
+      if (p !== q) return p - q;
+    }
+    return 0;
+  }
+
+  function sortableTable(columns, rows, rowClass) {
+    var table = el("table"), thead = el("thead"), tbody = el("tbody"), tr = el("tr");
+    var state = { col: 0, dir: 1 };
+    columns.forEach(function (c, idx) {
+      var th = el("th", {}, c.title);
+      th.onclick = function () {
+        state.dir = state.col === idx ? -state.dir : 1;
+        state.col = idx;
+        render();
+      };
+      tr.appendChild(th);
+    });
+    thead.appendChild(tr);
+    table.appendChild(thead);
+    table.appendChild(tbody);
+    function render() {
+      var c = columns[state.col];
+      var sorted = rows().slice().sort(function (a, b) {
+        var x = c.value(a), y = c.value(b);
+        return state.dir * (c.version ? cmpVersion(x, y) : String(x).localeCompare(String(y)));
+      });
+      Array.prototype.forEach.call(tr.children, function (th, idx) {
+        th.className = idx === state.col ? (state.dir > 0 ? "asc" : "desc") : "";
+      });
+      tbody.innerHTML = "";
+      sorted.forEach(function (r) {
+        var row = el("tr", rowClass ? { "class": rowClass(r) } : {});
+        columns.forEach(function (c) {
+          var td = el("td", c.cls ? { "class": c.cls } : {});
+          if (c.render) c.render(td, r); else td.textContent = c.value(r);
+          row.appendChild(td);
+        });
+        tbody.appendChild(row);
+      });
+    }
+    table.render = render;
+    render();
+    return table;
+  }
+
+  function podfileTab(pf) {
+    var box = el("div");
+    var counts = { common: 0, implicit: 0, local: 0, external: 0, up: 0, down: 0 };
+    pf.modules.forEach(function (m) { tags(m).forEach(function (t) { counts[t]++; }); });
+    var summary = el("div", { "class": "summary" });
+    summary.appendChild(el("span", {}, "模块: " + pf.modules.length));
+    for (var k in counts) summary.appendChild(el("span", {}, k + ": " + counts[k]));
+    summary.appendChild(el("span", {}, "仓库: " + (pf.repos.length ? pf.repos.join(" > ") : "全部")));
+    box.appendChild(summary);
+
+    var bar = el("div", { "class": "bar" });
+    var search = el("input", { type: "search", placeholder: "搜索模块" });
+    var filter = el("select");
+    ["all", "common", "implicit", "local", "external", "up", "down"].forEach(function (f) {
+      filter.appendChild(el("option", { value: f }, f === "all" ? "全部" : f));
+    });
+    bar.appendChild(search);
+    bar.appendChild(filter);
+    box.appendChild(bar);
+
+    function rows() {
+      var q = search.value.toLowerCase(), f = filter.value;
+      return pf.modules.filter(function (m) {
+        if (q && m.name.toLowerCase().indexOf(q) < 0) return false;
+        return f === "all" || tags(m).indexOf(f) > -1;
+      });
+    }
+    var table = sortableTable([
+      { title: "模块", value: function (m) { return m.name; } },
+      { title: "当前", value: function (m) { return m.version; }, version: true },
+      { title: "升级到", value: function (m) { return m.upgrade_to; }, version: true },
+      { title: "使用", value: function (m) { return m.use_version; }, version: true },
+      { title: "最新", value: function (m) { return m.newest; }, version: true },
+      { title: "标记", value: function (m) { return tags(m).join(" "); }, render: function (td, m) {
+        tags(m).forEach(function (t) { td.appendChild(el("span", { "class": "tag " + t }, t)); });
+      } },
+      { title: "依赖", value: function (m) { return m.dependencies.length; }, cls: "deps", render: function (td, m) {
+        td.textContent = m.dependencies.map(function (d) { return d.name + (d.requirement ? " " + d.requirement : ""); }).join(", ");
+      } }
+    ], rows);
+    search.oninput = filter.onchange = function () { table.render(); };
+    box.appendChild(table);
+    box.appendChild(graph(pf.modules));
+    return box;
+  }
+
+  function matrixTab() {
+    var box = el("div"), bar = el("div", { "class": "bar" });
+    var search = el("input", { type: "search", placeholder: "搜索模块" });
+    var onlyDiff = el("input", { type: "checkbox" });
+    var label = el("label");
+    label.appendChild(onlyDiff);
+    label.appendChild(document.createTextNode(" 仅显示版本不一致的模块"));
+    bar.appendChild(search);
+    bar.appendChild(label);
+    box.appendChild(bar);
+
+    var byName = {};
+    REPORT.forEach(function (pf, idx) {
+      pf.modules.forEach(function (m) {
+        byName[m.name] = byName[m.name] || { name: m.name, versions: [] };
+        byName[m.name].versions[idx] = m.use_version || "*";
+      });
+    });
+    var all = Object.keys(byName).map(function (k) {
+      var r = byName[k], seen = {};
+      for (var i = 0; i < REPORT.length; i++) seen[r.versions[i] || ""] = true;
+      r.differ = Object.keys(seen).length > 1;
+      return r;
+    });
+    var columns = [{ title: "模块", value: function (r) { return r.name; } }];
+    REPORT.forEach(function (pf, idx) {
+      columns.push({ title: baseName(pf.path), version: true, value: function (r) { return r.versions[idx] || ""; } });
+    });
+    var table = sortableTable(columns, function () {
+      var q = search.value.toLowerCase();
+      return all.filter(function (r) {
+        return (!q || r.name.toLowerCase().indexOf(q) > -1) && (!onlyDiff.checked || r.differ);
+      });
+    }, function (r) { return r.differ ? "differ" : ""; });
+    search.oninput = onlyDiff.onchange = function () { table.render(); };
+    box.appendChild(table);
+    return box;
+  }
+
+  // 简单的力导向布局，支持拖拽节点、滚轮缩放与悬停高亮
+  function graph(modules) {
+    var box = el("div", { "class": "graph" });
+    var W = 1200, H = 600, index = {}, nodes = [], links = [];
+    modules.forEach(function (m, i) {
+      index[m.name] = i;
+      nodes.push({ m: m, x: W / 2 + Math.cos(i) * 200 * Math.random(), y: H / 2 + Math.sin(i) * 200 * Math.random(), vx: 0, vy: 0 });
+    });
+    function resolve(name) {
+      while (name) {
+        if (index[name] !== undefined) return index[name];
+        var p = name.lastIndexOf("/");
+        name = p > 0 ? name.slice(0, p) : "";
+      }
+      return -1;
+    }
+    modules.forEach(function (m, i) {
+      m.dependencies.forEach(function (d) {
+        var j = resolve(d.name);
+        if (j > -1 && j !== i) links.push({ s: i, t: j, label: d.requirement });
+      });
+    });
+    for (var tick = 0; tick < 300; tick++) {
+      var alpha = 1 - tick / 300;
+      for (var i = 0; i < nodes.length; i++) {
+        for (var j = i + 1; j < nodes.length; j++) {
+          var a = nodes[i], b = nodes[j], dx = a.x - b.x, dy = a.y - b.y, d2 = dx * dx + dy * dy + 0.01;
+          var f = 800 / d2;
+          a.vx += dx * f; a.vy += dy * f; b.vx -= dx * f; b.vy -= dy * f;
+        }
+      }
+      links.forEach(function (l) {
+        var a = nodes[l.s], b = nodes[l.t], dx = b.x - a.x, dy = b.y - a.y;
+        var d = Math.sqrt(dx * dx + dy * dy) || 1, f = (d - 80) * 0.02;
+        a.vx += dx / d * f; a.vy += dy / d * f; b.vx -= dx / d * f; b.vy -= dy / d * f;
+      });
+      nodes.forEach(function (n) {
+        n.vx += (W / 2 - n.x) * 0.005; n.vy += (H / 2 - n.y) * 0.005;
+        n.x += Math.max(-20, Math.min(20, n.vx)) * alpha; n.y += Math.max(-20, Math.min(20, n.vy)) * alpha;
+        n.vx *= 0.5; n.vy *= 0.5;
+      });
+    }
+
+    var root = svg("svg", { viewBox: "0 0 " + W + " " + H });
+    var view = { x: 0, y: 0, w: W, h: H };
+    var lineEls = links.map(function (l) {
+      var line = svg("line");
+      line.appendChild(svg("title")).textContent = l.label || "";
+      root.appendChild(line);
+      return line;
+    });
+    var nodeEls = nodes.map(function (n, i) {
+      var g = svg("g"), c = svg("circle", { r: 7, fill: COLORS[kind(n.m)], "class": n.m.upgrade_tag });
+      var t = svg("text", { dx: 9, dy: 3 });
+      t.textContent = n.m.name + " " + (n.m.use_version || "");
+      g.appendChild(c);
+      g.appendChild(t);
+      g.onmouseenter = function () { highlight(i); };
+      g.onmouseleave = function () { highlight(-1); };
+      g.onmousedown = function (e) { drag = { i: i }; e.stopPropagation(); };
+      root.appendChild(g);
+      return g;
+    });
+    function layout() {
+      links.forEach(function (l, k) {
+        var a = nodes[l.s], b = nodes[l.t];
+        lineEls[k].setAttribute("x1", a.x); lineEls[k].setAttribute("y1", a.y);
+        lineEls[k].setAttribute("x2", b.x); lineEls[k].setAttribute("y2", b.y);
+      });
+      nodes.forEach(function (n, k) { nodeEls[k].setAttribute("transform", "translate(" + n.x + "," + n.y + ")"); });
+    }
+    function highlight(i) {
+      var near = {};
+      links.forEach(function (l) { if (l.s === i || l.t === i) { near[l.s] = near[l.t] = true; } });
+      nodeEls.forEach(function (g, k) { g.setAttribute("class", i < 0 || near[k] || k === i ? "" : "dim"); });
+      lineEls.forEach(function (line, k) {
+        var l = links[k], on = l.s === i || l.t === i;
+        line.setAttribute("class", i < 0 ? "" : (on ? "hl" : "dim"));
+      });
+    }
+    var drag = null;
+    function point(e) {
+      var r = root.getBoundingClientRect();
+      return { x: view.x + (e.clientX - r.left) / r.width * view.w, y: view.y + (e.clientY - r.top) / r.height * view.h };
+    }
+    root.onmousedown = function (e) { drag = { pan: point(e) }; };
+    root.onmousemove = function (e) {
+      if (!drag) return;
+      var p = point(e);
+      if (drag.pan) {
+        view.x -= p.x - drag.pan.x; view.y -= p.y - drag.pan.y;
+      } else {
+        nodes[drag.i].x = p.x; nodes[drag.i].y = p.y;
+        layout();
+      }
+      root.setAttribute("viewBox", [view.x, view.y, view.w, view.h].join(" "));
+    };
+    root.onmouseup = root.onmouseleave = function () { drag = null; };
+    root.onwheel = function (e) {
+      e.preventDefault();
+      var p = point(e), k = e.deltaY > 0 ? 1.1 : 0.9;
+      view.x = p.x - (p.x - view.x) * k; view.y = p.y - (p.y - view.y) * k;
+      view.w *= k; view.h *= k;
+      root.setAttribute("viewBox", [view.x, view.y, view.w, view.h].join(" "));
+    };
+    layout();
+    box.appendChild(root);
+    return box;
+  }
+
+  var pages = REPORT.map(function (pf) { return { title: baseName(pf.path), build: function () { return podfileTab(pf); } }; });
+  if (REPORT.length > 1) pages.push({ title: "版本矩阵", build: matrixTab });
+  pages.forEach(function (p, idx) {
+    var a = el("a", {}, p.title);
+    a.onclick = function () { show(idx); };
+    tabs.appendChild(a);
+  });
+  function show(idx) {
+    Array.prototype.forEach.call(tabs.children, function (a, k) { a.className = k === idx ? "active" : ""; });
+    content.innerHTML = "";
+    content.appendChild(pages[idx].build());
+  }
+  if (pages.length) show(0);
+})();
+</script>
+</body>
+</html>
+`
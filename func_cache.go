@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"pandora/pod"
+	"pandora/store"
+	"strconv"
+)
+
+func cmd_cache(aArgs *Args) {
+	ctx := _Ctx
+	args := aArgs.GetSubargsMain()
+	if len(args) > 0 && args[0] == "clear" {
+		n, e := _Client.Store().ClearSpecCache(ctx)
+		if e != nil {
+			exitWithMessage(e.Error(), false)
+		}
+		println("已清除Spec缓存: " + strconv.Itoa(n) + " 条")
+		return
+	}
+	n, e := _Client.Store().CountSpecCache(ctx)
+	if e != nil {
+		exitWithMessage(e.Error(), false)
+	}
+	println("Spec缓存: " + strconv.Itoa(n) + " 条")
+}
+
+// 基于存储的podspec求值缓存，读写失败时视为未命中
+type specCache struct {
+	store store.Store
+}
+
+func (s *specCache) Get(hash string) ([]byte, bool) {
+	json, ok, e := s.store.GetSpecCache(context.Background(), hash)
+	if e != nil || !ok {
+		return nil, false
+	}
+	return []byte(json), true
+}
+
+func (s *specCache) Put(hash string, b []byte) {
+	if e := s.store.PutSpecCache(context.Background(), hash, string(b)); e != nil {
+		printlnDebug("Warn: 写入Spec缓存失败 -> " + e.Error())
+	}
+}
+
+func printSpecCacheStats() {
+	stats := pod.GetSpecCacheStats()
+	if stats.Hit+stats.Miss == 0 {
+		return
+	}
+	println("Spec缓存: 命中 " + strconv.FormatInt(stats.Hit, 10) + " 次， 未命中 " + strconv.FormatInt(stats.Miss, 10) + " 次")
+}
@@ -5,6 +5,7 @@ import "pandora/pod"
 func cmd_test_spec(aArgs *Args) {
 	p := aArgs.GetFirstSubArgs("--p")
 	spec, e := pod.ReadSpec(p, true)
+	printSpecCacheStats()
 	if e != nil {
 		printRed(e.Error(), false)
 	} else {
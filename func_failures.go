@@ -0,0 +1,63 @@
+package main
+
+import (
+	"pandora/store"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func cmd_failures(aArgs *Args) {
+	failures, e := _Client.Store().ListFailures(_Ctx)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	out := newOutputFailures(failures)
+	if isJSONOutput() {
+		printJSON(out)
+		return
+	}
+	if out.Total == 0 {
+		println("没有解析失败的spec!")
+		return
+	}
+	println("解析失败的spec: " + strconv.Itoa(out.Total) + " 个，错误 " + strconv.Itoa(len(out.Groups)) + " 种")
+	for _, g := range out.Groups {
+		println("\n[" + strconv.Itoa(g.Count) + "] " + g.Error)
+		for _, f := range g.Specs {
+			println("   - " + f.Repo + "/" + f.Module + " " + f.Version + " [" + f.Category + "] " + f.Path + " (" + f.CTime + ")")
+		}
+	}
+	println("\n可执行 -sync --retry-failed 重新解析")
+}
+
+// 按错误信息分组，数量多的排在前面
+func newOutputFailures(failures []*store.Failure) *OutputFailures {
+	groups := make(map[string]*OutputFailureGroup)
+	out := &OutputFailures{Total: len(failures), Groups: make([]*OutputFailureGroup, 0, 10)}
+	for _, f := range failures {
+		msg := strings.TrimSpace(f.Error)
+		g, ok := groups[msg]
+		if !ok {
+			g = &OutputFailureGroup{Error: msg, Specs: make([]*OutputFailure, 0, 1)}
+			groups[msg] = g
+			out.Groups = append(out.Groups, g)
+		}
+		g.Count++
+		g.Specs = append(g.Specs, &OutputFailure{
+			Repo:     f.Repo,
+			Module:   f.Module,
+			Version:  f.Version,
+			Path:     f.Path,
+			Category: f.Category,
+			CTime:    f.CTime.Local().Format("2006-01-02 15:04:05"),
+		})
+	}
+	sort.SliceStable(out.Groups, func(i, j int) bool {
+		if out.Groups[i].Count != out.Groups[j].Count {
+			return out.Groups[i].Count > out.Groups[j].Count
+		}
+		return out.Groups[i].Error < out.Groups[j].Error
+	})
+	return out
+}
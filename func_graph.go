@@ -0,0 +1,89 @@
+package main
+
+import (
+	"os"
+	"pandora/pod"
+	"strconv"
+)
+
+func cmd_graph(aArgs *Args) {
+	args := aArgs.GetSubargsMain()
+	if len(args) < 2 {
+		exitWithMessage("参数错误！", true)
+	}
+	module := args[0]
+	version := args[1]
+	opt := readGraphExportOption(aArgs)
+
+	graphModule, e := _Client.Closure(_Ctx, module, version, opt.MaxDepth)
+	if e != nil {
+		exitWithMessage(e.Error(), false)
+	}
+	export := graphModule.Export([]string{module}, opt)
+	b := renderGraphExport(export, module+"@"+version, aArgs.GetFirstSubArgs("--type"), opt.MaxNodes)
+	if b == nil {
+		exitWithMessage("不支持的图格式！", true)
+	}
+	out := aArgs.GetFirstSubArgs("--out")
+	if out == "" {
+		os.Stdout.Write(b)
+		return
+	}
+	out = absolutePath(out)
+	if e := WriteFile(out, b, true, 0644); e != nil {
+		printRed("输出文件错误["+out+"]: "+e.Error(), false)
+		return
+	}
+	println("已输出: " + out)
+}
+
+func readGraphExportOption(aArgs *Args) *pod.GraphExportOption {
+	opt := new(pod.GraphExportOption)
+	opt.CollapseSubspecs = aArgs.CheckSubargs("--collapse")
+	if d, e := strconv.Atoi(aArgs.GetFirstSubArgs("--depth")); e == nil && d > 0 {
+		opt.MaxDepth = d
+	}
+	if n, e := strconv.Atoi(aArgs.GetFirstSubArgs("--max_nodes")); e == nil && n > 0 {
+		opt.MaxNodes = n
+	}
+	return opt
+}
+
+// 按格式渲染依赖图，不支持的格式返回nil；DOT仅在指定maxNodes时裁剪
+func renderGraphExport(export *pod.GraphExport, name string, format string, maxNodes int) []byte {
+	switch format {
+	case "", "dot":
+		if maxNodes > 0 {
+			export, _, _ = export.Limit(maxNodes)
+		}
+		return export.DOT(name)
+	case "mermaid":
+		return export.Mermaid(name, maxNodes)
+	case "plantuml":
+		return export.PlantUML(name, maxNodes)
+	}
+	return nil
+}
+
+var _GraphExportFormats = []struct {
+	Option string
+	Format string
+	Ext    string
+}{
+	{"--dot", "dot", ".dot"},
+	{"--mermaid", "mermaid", ".mmd"},
+	{"--plantuml", "plantuml", ".puml"},
+}
+
+// 输出-up分析得到的依赖图，ext为文件扩展名
+func writeGraphExport(filePath string, graphPodfile pod.GraphPodfile, opt *pod.GraphExportOption, format string, ext string) {
+	name := podfileName(filePath)
+	b := renderGraphExport(graphPodfile.Export(nil, opt), name, format, opt.MaxNodes)
+	if b == nil {
+		return
+	}
+	p := filePath + ext
+	if e := WriteFile(p, b, true, os.ModePerm); e != nil {
+		printRed("输出文件错误["+p+"]: "+e.Error(), false)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"pandora/store"
+	"strconv"
+	"strings"
+)
+
+const __LOG_DEFAULT_LIMIT = 20
+
+// -log [--limit N] 列出最近的同步，-log <id> 查看某次同步的详情
+func cmd_log(aArgs *Args) {
+	if id := aArgs.GetFirstSubArgsMain(); id != "" {
+		syncID, e := strconv.ParseInt(id, 10, 64)
+		if e != nil {
+			exitWithOutputError("同步ID不正确: "+id, false)
+		}
+		printSyncLogDetail(syncID)
+		return
+	}
+	limit := __LOG_DEFAULT_LIMIT
+	if l := aArgs.GetFirstSubArgs("--limit"); l != "" {
+		n, e := strconv.Atoi(l)
+		if e != nil || n < 1 {
+			exitWithOutputError("--limit 须为正整数", false)
+		}
+		limit = n
+	}
+	logs, e := _Client.Store().ListSyncLogs(_Ctx, limit)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	out := make([]*OutputSyncLog, 0, len(logs))
+	for _, l := range logs {
+		out = append(out, newOutputSyncLog(l))
+	}
+	if isJSONOutput() {
+		printJSON(out)
+		return
+	}
+	if len(out) == 0 {
+		println("暂无同步记录!")
+		return
+	}
+	for _, l := range out {
+		println(syncLogLine(l))
+	}
+	println("\n可执行 -log <ID> 查看详情")
+}
+
+func printSyncLogDetail(id int64) {
+	l, e := _Client.Store().GetSyncLog(_Ctx, id)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	if l == nil {
+		exitWithOutputError("同步记录不存在: "+strconv.FormatInt(id, 10), false)
+	}
+	stats, e := _Client.Store().ListRepoStats(_Ctx, id)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	changes, e := _Client.Store().ListChanges(_Ctx, id)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	out := newOutputSyncLog(l)
+	out.Repos = make([]*OutputSyncLogRepo, 0, len(stats))
+	for _, s := range stats {
+		out.Repos = append(out.Repos, &OutputSyncLogRepo{
+			Name:             s.Repo,
+			Success:          s.Success,
+			MissingSpec:      s.MissingSpec,
+			ParseFailure:     s.ParseFailure,
+			EvaluatorFailure: s.EvaluatorFailure,
+			DuplicateKey:     s.DuplicateKey,
+			GitHead:          s.GitHead,
+		})
+	}
+	out.Changes = make([]*OutputSyncLogChange, 0, len(changes))
+	for _, c := range changes {
+		out.Changes = append(out.Changes, &OutputSyncLogChange{Action: c.Action, Repo: c.Repo, Module: c.Module, Version: c.Version})
+	}
+	if isJSONOutput() {
+		printJSON(out)
+		return
+	}
+	println(syncLogLine(out))
+	println("pandora版本: " + out.Version + " 配置摘要: " + out.ConfigHash)
+	if len(out.Repos) > 0 {
+		println("\n仓库统计：")
+		for _, r := range out.Repos {
+			head := r.GitHead
+			if head == "" {
+				head = "-"
+			}
+			println("   " + r.Name + " (" + head + "): 成功 " + strconv.Itoa(r.Success) +
+				"，缺少spec文件 " + strconv.Itoa(r.MissingSpec) +
+				"，解析失败 " + strconv.Itoa(r.ParseFailure) +
+				"，求值失败 " + strconv.Itoa(r.EvaluatorFailure) +
+				"，重复主键 " + strconv.Itoa(r.DuplicateKey))
+		}
+	}
+	titles := []struct{ action, title, mark string }{
+		{store.CHANGE_ADDED, "出现", "+"},
+		{store.CHANGE_CHANGED, "变化", "~"},
+		{store.CHANGE_REMOVED, "消失", "-"},
+	}
+	for _, t := range titles {
+		lines := make([]string, 0, 10)
+		for _, c := range out.Changes {
+			if c.Action == t.action {
+				lines = append(lines, "   "+t.mark+" "+c.Repo+"/"+c.Module+" "+c.Version)
+			}
+		}
+		if len(lines) > 0 {
+			println("\n" + t.title + "的版本 (" + strconv.Itoa(len(lines)) + ")：")
+			println(strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func syncLogLine(l *OutputSyncLog) string {
+	return "[" + strconv.FormatInt(l.ID, 10) + "] " + l.Time + " " + l.Status + " 耗时 " + l.Duration +
+		" 新增 " + strconv.Itoa(l.Added) + " 变化 " + strconv.Itoa(l.Changed) +
+		" 移除 " + strconv.Itoa(l.Removed) + " 失败 " + strconv.Itoa(l.Failed) +
+		" (" + l.Version + ", " + l.ConfigHash + ")"
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"errors"
+	"pandora/pod"
+	"pandora/store"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -news [--since <date>|--last-sync] [--podfile <path>] 列出ctime在指定时间之后的模块版本
+func cmd_news(aArgs *Args) {
+	since, e := readNewsSince(aArgs)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	var modules map[string]bool
+	if podfiles := aArgs.GetSubargs("--podfile"); len(podfiles) > 0 {
+		if modules, e = readPodfileModules(podfiles); e != nil {
+			exitWithOutputError(e.Error(), false)
+		}
+	}
+	records, e := _Client.Store().ListIndex(_Ctx)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	out := newOutputNews(records, since, modules)
+	if isJSONOutput() {
+		printJSON(out)
+		return
+	}
+	println(out.Since + " 之后入库的版本: " + strconv.Itoa(out.Total) + " 个，涉及模块 " + strconv.Itoa(len(out.Modules)) + " 个")
+	var major int
+	for _, m := range out.Modules {
+		previous := m.Previous
+		if previous == "" {
+			previous = "新模块"
+		}
+		line := "   " + m.Repo + "/" + m.Module + " (" + previous + ") -> " + strings.Join(m.Versions, ", ")
+		if m.Major {
+			major++
+			printRed(line+" [主版本升级]", false)
+		} else {
+			println(line)
+		}
+	}
+	if major > 0 {
+		printRed("主版本升级的模块: "+strconv.Itoa(major)+" 个，升级前请关注不兼容的变更", false)
+	}
+}
+
+// 默认及--last-sync时取最近一次完成的同步开始的时间，该次同步入库的版本ctime等于该时间
+func readNewsSince(aArgs *Args) (time.Time, error) {
+	s := aArgs.GetFirstSubArgs("--since")
+	if s == "" {
+		if aArgs.CheckSubargs("--since") {
+			return time.Time{}, errors.New("请指定--since的日期，格式 2006-01-02 或 2006-01-02 15:04:05")
+		}
+		t, e := _Client.Store().LastSync(_Ctx)
+		if e != nil {
+			return t, e
+		}
+		if t.IsZero() {
+			return t, errors.New("暂无完成的同步记录，请先执行 -sync 或指定 --since")
+		}
+		return t, nil
+	}
+	if aArgs.CheckSubargs("--last-sync") {
+		return time.Time{}, errors.New("--since 与 --last-sync 不能同时使用")
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02"} {
+		if t, e := time.ParseInLocation(layout, s, time.Local); e == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("日期格式不正确: " + s + "，格式 2006-01-02 或 2006-01-02 15:04:05")
+}
+
+// Podfile使用的全部模块，与-up相同按Podfile的source解析依赖图，包含间接依赖，子模块取基础模块
+func readPodfileModules(podfiles []string) (map[string]bool, error) {
+	res := make(map[string]bool)
+	for _, p := range podfiles {
+		aPodfile, e := pod.NewPodfile(_Ctx, absolutePath(p), true)
+		if e != nil {
+			return nil, e
+		}
+		for name := range buildGraphPodfiles(_Ctx, aPodfile, nil, resolvePodfileRepos(aPodfile)) {
+			res[pod.BaseModule(name)] = true
+		}
+	}
+	return res, nil
+}
+
+// 按仓库/模块汇总since之后入库的版本，与之前已有的最新版本比较判断是否为主版本升级
+func newOutputNews(records []*store.Record, since time.Time, modules map[string]bool) *OutputNews {
+	out := &OutputNews{Since: since.Local().Format("2006-01-02 15:04:05"), Modules: make([]*OutputNewsModule, 0, 10)}
+	m := make(map[string]*OutputNewsModule)
+	previous := make(map[string][]string)
+	for _, r := range records {
+		if modules != nil && !modules[r.Module] {
+			continue
+		}
+		key := r.Repo + "/" + r.Module
+		if r.CTime.Before(since) {
+			previous[key] = append(previous[key], r.Version)
+			continue
+		}
+		aModule, ok := m[key]
+		if !ok {
+			aModule = &OutputNewsModule{Repo: r.Repo, Module: r.Module, Versions: make([]string, 0, 1)}
+			m[key] = aModule
+			out.Modules = append(out.Modules, aModule)
+		}
+		aModule.Versions = append(aModule.Versions, r.Version)
+		out.Total++
+	}
+	for key, aModule := range m {
+		sort.Slice(aModule.Versions, func(i, j int) bool {
+			return pod.CompareVersion(aModule.Versions[i], aModule.Versions[j]) < 0
+		})
+		if versions := previous[key]; len(versions) > 0 {
+			aModule.Previous, _ = pod.MaxVersion("", versions...)
+		}
+		if aModule.Previous != "" {
+			aModule.Major = pod.IsMajorUpgrade(aModule.Previous, aModule.Versions[len(aModule.Versions)-1])
+		}
+	}
+	sort.Slice(out.Modules, func(i, j int) bool {
+		if out.Modules[i].Major != out.Modules[j].Major {
+			return out.Modules[i].Major
+		}
+		if out.Modules[i].Repo != out.Modules[j].Repo {
+			return out.Modules[i].Repo < out.Modules[j].Repo
+		}
+		return out.Modules[i].Module < out.Modules[j].Module
+	})
+	return out
+}
@@ -1,16 +1,13 @@
 package main
 
 import (
-	"errors"
-	"pandora/pod"
+	"encoding/json"
+	"pandora/client"
 
 	"bytes"
 
-	"database/sql"
-
 	"strings"
-
-	ver "github.com/hashicorp/go-version"
+	"sync"
 )
 
 func cmd_depend(aArgs *Args) {
@@ -24,102 +21,56 @@ func cmd_depend(aArgs *Args) {
 	module := args[0]
 	version := args[1]
 
-	deps, repoList, err := queryDepends(module, version)
+	res, err := _Client.Depends(_Ctx, module, version)
 	if err != nil {
-		printRed(err.Error(), false)
+		exitWithOutputError(err.Error(), false)
+	}
+	if isJSONOutput() {
+		printJSON(newOutputDependsResult(res))
 		return
 	}
-	if len(deps) == 0 {
+	if len(res.Depends) == 0 {
 		println("未查询到依赖!")
 		return
 	}
-	println("-> 仓库: " + repoList + "  模块: " + module + "  版本: " + version)
-	for _, aDep := range deps {
+	println("-> 仓库: [" + strings.Join(res.Repos, "][") + "]  模块: " + module + "  版本: " + version)
+	for _, aDep := range res.Depends {
 		println("   - " + aDep.N + "  " + aDep.V)
 	}
 	println("")
 }
 
-func queryDepends(module string, version string) ([]*pod.DependBase, string, error) {
-	if module == "" || version == "" {
-		return nil, "", errors.New("模块名和版本号不能为空！")
-	}
-	baseModule := pod.BaseModule(module)
-	var rows *sql.Rows
-	var e error
-	if rows, e = _DB.Query(_SQL_QUERY_SPEC, baseModule, version); e != nil {
-		return nil, "", e
+var _SpecConflictWarned = make(map[string]bool)
+var _SpecConflictLock sync.Mutex
+
+// 同一模块版本在多个仓库中内容不同时输出警告及差异，每个模块版本仅警告一次
+func warnSpecConflict(c *client.SpecConflict) {
+	key := c.Module + "@" + c.Version
+	_SpecConflictLock.Lock()
+	defer _SpecConflictLock.Unlock()
+	if _SpecConflictWarned[key] {
+		return
 	}
-	var buffer bytes.Buffer
-	var spec *pod.Spec
-	for rows.Next() {
-		var repo, jsonString string
-		if e = rows.Scan(&repo, &jsonString); e != nil {
+	first := c.Specs[0]
+	for _, aSpec := range c.Specs[1:] {
+		if aSpec.JSON == first.JSON {
 			continue
 		}
-		buffer.WriteString("[" + repo + "]")
-		if spec == nil && jsonString != "" {
-			spec, _ = pod.NewSpecWithJSONString(jsonString)
-		}
-	}
-	if spec == nil {
-		return nil, "", nil
-	}
-	deps := spec.GetAllDepends(module)
-	l := len(deps)
-	if l == 0 {
-		return nil, "", nil
-	}
-	cap := l/2 + 1
-	resHead := make([]*pod.DependBase, 0, cap)
-	resFoot := make([]*pod.DependBase, 0, cap)
-	baseModule += "/"
-	for key, val := range deps {
-		aDep := pod.DependBase{N: key, V: val}
-		if strings.HasPrefix(key, baseModule) {
-			resHead = append(resHead, &aDep)
-		} else {
-			resFoot = append(resFoot, &aDep)
+		a, b := indentJSON(first.JSON), indentJSON(aSpec.JSON)
+		diff := UnifiedDiff(first.Repo+"/"+key, a, aSpec.Repo+"/"+key, b)
+		if diff == "" {
+			continue
 		}
+		_SpecConflictWarned[key] = true
+		printRed("Warn: "+key+" 在多个仓库中的spec不同，使用 ["+first.Repo+"]", false)
+		printDiff(diff)
 	}
-	return append(resHead, resFoot...), buffer.String(), nil
-}
-
-func queryNewestVersion(module string, constraint string) (string, error) {
-	versions, e := queryVersion(module, constraint)
-	if e != nil {
-		return "", e
-	}
-	return pod.MaxVersion("", versions...)
 }
 
-func queryVersion(module string, contraint string) ([]string, error) {
-	var aConstraint ver.Constraints
-	if len(contraint) > 0 {
-		aConstraint, _ = ver.NewConstraint(contraint)
-	}
-	rows, e := _DB.Query(_SQL_QUERY_VERSIONS, pod.BaseModule(module))
-	if e != nil {
-		return nil, e
-	}
-	m := make(map[string]bool)
-	for rows.Next() {
-		var v string
-		e = rows.Scan(&v)
-		if e != nil {
-			continue
-		}
-		if aConstraint != nil {
-			aVer, e := ver.NewVersion(v)
-			if e != nil || !aConstraint.Check(aVer) {
-				continue
-			}
-		}
-		m[v] = true
-	}
-	res := make([]string, 0, len(m))
-	for v, _ := range m {
-		res = append(res, v)
+func indentJSON(s string) []byte {
+	var buffer bytes.Buffer
+	if e := json.Indent(&buffer, []byte(s), "", "  "); e != nil {
+		return []byte(s)
 	}
-	return res, nil
+	return buffer.Bytes()
 }
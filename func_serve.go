@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"pandora/client"
+	"pandora/pod"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// 默认仅监听本机，对外提供服务需显式指定--addr
+const __SERVE_DEFAULT_ADDR = "127.0.0.1:8080"
+
+type serveSyncState struct {
+	Running  bool        `json:"running"`
+	Started  string      `json:"started,omitempty"`
+	Finished string      `json:"finished,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Result   *OutputSync `json:"result,omitempty"`
+}
+
+type serveVersions struct {
+	Module     string   `json:"module"`
+	Constraint string   `json:"constraint"`
+	Versions   []string `json:"versions"`
+}
+
+type serveNewest struct {
+	Module     string `json:"module"`
+	Constraint string `json:"constraint"`
+	Version    string `json:"version"`
+}
+
+type serveModules struct {
+	Query   string   `json:"query"`
+	Modules []string `json:"modules"`
+}
+
+type serveDependents struct {
+	Module     string            `json:"module"`
+	Version    string            `json:"version"`
+	Dependents []*serveDependent `json:"dependents"`
+}
+
+type serveDependent struct {
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+var (
+	_ServeDataVersion atomic.Value
+	_ServeSyncRunning int32
+	_ServeSyncState   = new(serveSyncState)
+	_ServeSyncLock    sync.Mutex
+	_ServeSyncWait    sync.WaitGroup
+
+	// 配置--token时POST /api/sync需携带 Authorization: Bearer <token>；否则需--allow_sync才允许触发同步
+	_ServeSyncToken string
+	_ServeAllowSync bool
+)
+
+func cmd_serve(aArgs *Args) {
+	addr := aArgs.GetFirstSubArgs("--addr")
+	if addr == "" {
+		addr = __SERVE_DEFAULT_ADDR
+	}
+	_ServeSyncToken = aArgs.GetFirstSubArgs("--token")
+	_ServeAllowSync = aArgs.CheckSubargs("--allow_sync")
+	refreshDataVersion()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/search", serveQuery(handleSearch))
+	mux.HandleFunc("/api/versions", serveQuery(handleVersions))
+	mux.HandleFunc("/api/newest", serveQuery(handleNewest))
+	mux.HandleFunc("/api/dependencies", serveQuery(handleDependencies))
+	mux.HandleFunc("/api/dependents", serveQuery(handleDependents))
+	mux.HandleFunc("/api/spec", serveQuery(handleSpec))
+	mux.HandleFunc("/api/sync", handleSync)
+
+	// 收到中断信号时停止接收新请求，等待进行中的请求及同步结束
+	server := &http.Server{Addr: addr, Handler: mux}
+	go func() {
+		<-_Ctx.Done()
+		server.Shutdown(context.Background())
+	}()
+	printGreen("开始监听: "+addr, false)
+	if e := server.ListenAndServe(); e != nil && e != http.ErrServerClosed {
+		exitWithMessage(e.Error(), false)
+	}
+	_ServeSyncWait.Wait()
+}
+
+// 数据版本取最后一次同步时间，用于生成ETag
+func refreshDataVersion() {
+	_DBLock.RLock()
+	defer _DBLock.RUnlock()
+	t, e := _Client.LastSync(context.Background())
+	if e != nil || t.IsZero() {
+		t = time.Now()
+	}
+	_ServeDataVersion.Store(strconv.FormatInt(t.UnixNano(), 10))
+}
+
+type serveHandler func(r *http.Request) (interface{}, int, error)
+
+// 只读查询：持有读锁执行查询，按数据版本及请求地址生成ETag
+func serveQuery(h serveHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			writeServeError(w, http.StatusMethodNotAllowed, "仅支持GET请求")
+			return
+		}
+		// 同步过程中每提交一个批次数据即发生变化
+		batches := strconv.FormatInt(atomic.LoadInt64(&_SyncBatches), 10)
+		sum := md5.Sum([]byte(_ServeDataVersion.Load().(string) + "\x00" + batches + "\x00" + r.URL.RequestURI()))
+		etag := "\"" + hex.EncodeToString(sum[:]) + "\""
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		_DBLock.RLock()
+		v, code, e := h(r)
+		_DBLock.RUnlock()
+		if e != nil {
+			writeServeError(w, code, e.Error())
+			return
+		}
+		if s, ok := v.(string); ok {
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(code)
+			w.Write([]byte(s))
+			return
+		}
+		writeServeJSON(w, code, v)
+	}
+}
+
+func writeServeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeServeError(w http.ResponseWriter, code int, msg string) {
+	w.Header().Del("ETag")
+	writeServeJSON(w, code, &OutputError{Error: msg})
+}
+
+func serveParamError(name string) (interface{}, int, error) {
+	return nil, http.StatusBadRequest, errServeParam(name)
+}
+
+type errServeParam string
+
+func (s errServeParam) Error() string {
+	return "缺少参数: " + string(s)
+}
+
+// ** Handler **
+
+// GET /api/search?q=AFN&limit=50
+func handleSearch(r *http.Request) (interface{}, int, error) {
+	q := r.URL.Query().Get("q")
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	modules, e := _Client.Modules(r.Context(), q, limit)
+	if e != nil {
+		return nil, http.StatusInternalServerError, e
+	}
+	return &serveModules{Query: q, Modules: modules}, http.StatusOK, nil
+}
+
+// GET /api/versions?module=AFNetworking&constraint=~>3.0
+func handleVersions(r *http.Request) (interface{}, int, error) {
+	module, constraint := r.URL.Query().Get("module"), r.URL.Query().Get("constraint")
+	if module == "" {
+		return serveParamError("module")
+	}
+	versions, e := _Client.Versions(r.Context(), module, constraint)
+	if e != nil {
+		return nil, http.StatusInternalServerError, e
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return pod.CompareVersion(versions[i], versions[j]) > 0
+	})
+	return &serveVersions{Module: module, Constraint: constraint, Versions: versions}, http.StatusOK, nil
+}
+
+// GET /api/newest?module=AFNetworking&constraint=~>3.0
+func handleNewest(r *http.Request) (interface{}, int, error) {
+	module, constraint := r.URL.Query().Get("module"), r.URL.Query().Get("constraint")
+	if module == "" {
+		return serveParamError("module")
+	}
+	v, e := _Client.NewestVersion(r.Context(), module, constraint)
+	if e != nil {
+		return nil, http.StatusInternalServerError, e
+	}
+	if v == "" {
+		return nil, http.StatusNotFound, errServeNotFound(module)
+	}
+	return &serveNewest{Module: module, Constraint: constraint, Version: v}, http.StatusOK, nil
+}
+
+// GET /api/dependencies?module=AFNetworking&version=3.2.1
+func handleDependencies(r *http.Request) (interface{}, int, error) {
+	module, version := r.URL.Query().Get("module"), r.URL.Query().Get("version")
+	if module == "" || version == "" {
+		return serveParamError("module, version")
+	}
+	res, e := _Client.Depends(r.Context(), module, version)
+	if e != nil {
+		return nil, http.StatusInternalServerError, e
+	}
+	if len(res.Repos) == 0 {
+		return nil, http.StatusNotFound, errServeNotFound(module + "@" + version)
+	}
+	return newOutputDependsResult(res), http.StatusOK, nil
+}
+
+// GET /api/dependents?module=AFNetworking[&version=3.2.1]
+func handleDependents(r *http.Request) (interface{}, int, error) {
+	module, version := r.URL.Query().Get("module"), r.URL.Query().Get("version")
+	if module == "" {
+		return serveParamError("module")
+	}
+	deps, e := _Client.Dependents(r.Context(), module, version)
+	if e != nil {
+		return nil, http.StatusInternalServerError, e
+	}
+	res := make([]*serveDependent, 0, len(deps))
+	for _, aDep := range deps {
+		res = append(res, &serveDependent{Module: aDep.N, Version: aDep.V})
+	}
+	return &serveDependents{Module: module, Version: version, Dependents: res}, http.StatusOK, nil
+}
+
+// GET /api/spec?module=AFNetworking&version=3.2.1，返回原始spec JSON
+func handleSpec(r *http.Request) (interface{}, int, error) {
+	module, version := r.URL.Query().Get("module"), r.URL.Query().Get("version")
+	if module == "" || version == "" {
+		return serveParamError("module, version")
+	}
+	spec, e := _Client.Spec(r.Context(), module, version)
+	if e == client.ErrNotFound {
+		return nil, http.StatusNotFound, errServeNotFound(module + "@" + version)
+	}
+	if e != nil {
+		return nil, http.StatusInternalServerError, e
+	}
+	return spec.JSON, http.StatusOK, nil
+}
+
+type errServeNotFound string
+
+func (s errServeNotFound) Error() string {
+	return "未找到: " + string(s)
+}
+
+// GET /api/sync 查询同步状态；POST /api/sync 在后台触发同步，同一时间仅允许一个同步
+func handleSync(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		_ServeSyncLock.Lock()
+		state := *_ServeSyncState
+		_ServeSyncLock.Unlock()
+		writeServeJSON(w, http.StatusOK, &state)
+	case http.MethodPost:
+		if code, msg := authorizeSync(r); code != http.StatusOK {
+			writeServeError(w, code, msg)
+			return
+		}
+		if !atomic.CompareAndSwapInt32(&_ServeSyncRunning, 0, 1) {
+			writeServeError(w, http.StatusConflict, "同步正在进行中")
+			return
+		}
+		_ServeSyncLock.Lock()
+		_ServeSyncState = &serveSyncState{Running: true, Started: time.Now().Format(time.RFC3339)}
+		state := *_ServeSyncState
+		_ServeSyncLock.Unlock()
+		_ServeSyncWait.Add(1)
+		go serveSync()
+		writeServeJSON(w, http.StatusAccepted, &state)
+	default:
+		writeServeError(w, http.StatusMethodNotAllowed, "仅支持GET或POST请求")
+	}
+}
+
+// 触发同步会写入数据库，需配置--token并校验请求，或以--allow_sync显式开启
+func authorizeSync(r *http.Request) (int, string) {
+	if _ServeSyncToken != "" {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "Bearer ") {
+			return http.StatusUnauthorized, "缺少Authorization: Bearer <token>"
+		}
+		if subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(_ServeSyncToken)) != 1 {
+			return http.StatusForbidden, "token无效"
+		}
+		return http.StatusOK, ""
+	}
+	if !_ServeAllowSync {
+		return http.StatusForbidden, "未开启远程同步，启动时添加--allow_sync或--token"
+	}
+	return http.StatusOK, ""
+}
+
+func serveSync() {
+	defer _ServeSyncWait.Done()
+	defer atomic.StoreInt32(&_ServeSyncRunning, 0)
+	out, e := runSync(_Ctx, __SYNC_MODE_NORMAL)
+	refreshDataVersion()
+	_ServeSyncLock.Lock()
+	defer _ServeSyncLock.Unlock()
+	_ServeSyncState.Running = false
+	_ServeSyncState.Finished = time.Now().Format(time.RFC3339)
+	_ServeSyncState.Result = out
+	if e != nil {
+		_ServeSyncState.Error = e.Error()
+	}
+}
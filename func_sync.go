@@ -1,103 +1,293 @@
 package main
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"os"
 	"pandora/pod"
+	"pandora/store"
 	"path"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	cp "github.com/fatih/color"
 	"github.com/go-hayden-base/str"
 )
 
+const (
+	__SYNC_MODE_NORMAL = iota
+	// 继续最近一次未完成的同步
+	__SYNC_MODE_RESUME
+	// 仅重新解析之前解析失败的spec
+	__SYNC_MODE_RETRY_FAILED
+)
+
+type syncMode int
+
 func cmd_sync(args *Args) {
+	var mode syncMode = __SYNC_MODE_NORMAL
+	resume, retry := args.CheckSubargs("--resume"), args.CheckSubargs("--retry-failed")
+	if resume && retry {
+		exitWithOutputError("--resume 与 --retry-failed 不能同时使用！", false)
+	}
+	if resume {
+		mode = __SYNC_MODE_RESUME
+	} else if retry {
+		mode = __SYNC_MODE_RETRY_FAILED
+	}
+	out, e := runSync(_Ctx, mode)
+	if e != nil {
+		exitWithOutputError(e.Error(), false)
+	}
+	if isJSONOutput() {
+		printJSON(out)
+	}
+}
+
+// 索引本地Pod仓库并同步到数据库，ctx被取消时已提交的批次保留，本次同步记为中断
+// 已索引的版本在spec文件修改后重新解析，版本目录已删除的记录在同步完成时移除
+// __SYNC_MODE_RESUME继续最近一次未完成的同步，跳过已记录检查点的模块
+// __SYNC_MODE_RETRY_FAILED仅重新解析spec_failure中记录的spec
+func runSync(ctx context.Context, mode syncMode) (*OutputSync, error) {
 	println("准备数据...")
-	excluedKeyMap, e := readExistKeys()
+	existing, e := readExistRecords(ctx)
 	if e != nil {
-		cp.Red(e.Error())
-		return
+		return nil, e
+	}
+	var session *syncSession
+	checkpoints := make(map[string]bool)
+	var retryKeys, retryModules map[string]bool
+	switch mode {
+	case __SYNC_MODE_RESUME:
+		if session, checkpoints, e = readResumeSession(ctx); e != nil {
+			return nil, e
+		}
+	case __SYNC_MODE_RETRY_FAILED:
+		if retryKeys, retryModules, e = readRetryFailures(ctx); e != nil {
+			return nil, e
+		}
+		if len(retryKeys) == 0 {
+			println("没有解析失败的spec!")
+			return newEmptyOutputSync(), nil
+		}
 	}
 
-	excludeModuleMap := readExcluedModule()
+	filters := readRepoFilters()
+	walked := &syncWalked{versions: make(map[string]bool), skipped: make(map[string]bool)}
 
 	filerFunc := func(p string, level pod.PodLevel) bool {
+		rn := podPathRepo(p)
 		switch level {
 		case pod.ENUM_POD_LEVEL_MODULE:
 			mn := path.Base(p)
-			rn := path.Base(path.Dir(p))
-			if r, ok := excludeModuleMap[rn]; ok {
-				if _, ok := r[mn]; ok {
-					printYellow("忽略模块: "+rn+"/"+mn, false)
-					return true
-				}
+			if checkpoints[rn+"/"+mn] {
+				walked.skipModule(rn + "/" + mn)
+				return true
+			}
+			if retryModules != nil && !retryModules[mn] {
+				return true
+			}
+			if filters[rn].skipModule(mn) {
+				printlnDebug("忽略模块: " + rn + "/" + mn)
+				return true
 			}
 		case pod.ENUM_POD_LEVEL_VERSION:
 			md5 := str.MD5(p)
-			if _, ok := excluedKeyMap[md5]; ok {
+			if retryKeys != nil && !retryKeys[md5] {
 				return true
 			}
+			if filters[rn].skipVersion(path.Base(path.Dir(p)), path.Base(p)) {
+				return true
+			}
+			walked.addVersion(md5)
+			if retryKeys != nil {
+				return false
+			}
+			if r, ok := existing[md5]; ok {
+				return !specModified(r)
+			}
 		}
 		return false
 	}
 
 	repos := readRepos()
 	println("开始索引Pod...")
-	p, success, failure, e := pod.PodIndex(_Conf.PodRepoRoot, repos, _Conf.SpecThread, true, filerFunc)
+	p, result, e := pod.PodWalk(ctx, _Conf.PodRepoRoot, repos, filerFunc)
+	if e == context.Canceled {
+		return nil, errSyncCanceled
+	}
 	if e != nil {
-		cp.Red(e.Error())
-		return
+		return nil, e
 	}
-	if len(p.PodRepos) == 0 {
+	var removed []*store.Record
+	if mode != __SYNC_MODE_RETRY_FAILED && len(result.Errors) == 0 {
+		removed = removedRecords(existing, repos, walked)
+	}
+	exclusions := readExclusions(filters)
+	printExclusions(exclusions)
+	total := p.VersionCount()
+	if total == 0 && len(removed) == 0 && session == nil {
 		println("暂时没有需要更新的Pod，请尝试执行pod update更新指定仓库后在尝试索引!")
-		return
+		return newEmptyOutputSync(), nil
 	}
-	println("解析成功：" + strconv.Itoa(success) + " 解析失败：" + strconv.Itoa(failure))
+	if session == nil {
+		if session, e = startSyncSession(ctx); e != nil {
+			return nil, e
+		}
+	}
+	session.heads = readRepoHeads()
 
-	println("开始同步到数据库...")
-	suc, fail := syncToDB(p)
+	println("开始解析Spec并同步到数据库，共 " + strconv.Itoa(total) + " 个版本...")
+	suc, fail, e := syncToDB(ctx, p, result, session, existing, removed)
+	if e != nil {
+		interruptSyncSession(session)
+		return nil, e
+	}
+	success, failure := result.Success(), result.Failure("")-result.Failure(pod.INDEX_ERR_DUPLICATE_KEY)
+	println("解析成功：" + strconv.Itoa(success) + " 解析失败：" + strconv.Itoa(failure))
+	printSpecCacheStats()
+	timeouts := podTimeoutSpecs(p)
+	printTimeoutSpecs(timeouts)
 	println("同步数据： 成功 " + strconv.Itoa(suc) + " 条， 失败 " + strconv.Itoa(fail) + " 条")
+	l := session.log
+	println("版本变化： 新增 " + strconv.Itoa(l.Added) + " 个， 变化 " + strconv.Itoa(l.Changed) + " 个， 移除 " + strconv.Itoa(l.Removed) + " 个")
+	printIndexResult(result)
+	stats := pod.GetSpecCacheStats()
+	out := &OutputSync{SyncID: l.ID, Resumed: session.resumed, Success: success, Failure: failure, Inserted: suc, Failed: fail, Added: l.Added, Changed: l.Changed, Removed: l.Removed, CacheHit: stats.Hit, CacheMiss: stats.Miss, Timeouts: timeouts}
+	out.Repos, out.Failures = newOutputSyncRepos(p, result)
+	out.Exclusions = exclusions
+	out.Errors = make([]string, 0, len(result.Errors))
+	for _, e := range result.Errors {
+		out.Errors = append(out.Errors, e.Error())
+	}
+	return out, nil
+}
+
+// 按仓库输出索引统计及仓库级别的错误
+func printIndexResult(result *pod.IndexResult) {
+	println("仓库统计：")
+	for _, stats := range result.Repos {
+		println("   " + stats.Name + ": 成功 " + strconv.Itoa(stats.Success) +
+			"，无spec版本 " + strconv.Itoa(stats.NoSpec) +
+			"，缺少spec文件 " + strconv.Itoa(stats.Failures[pod.INDEX_ERR_MISSING_SPEC]) +
+			"，解析失败 " + strconv.Itoa(stats.Failures[pod.INDEX_ERR_PARSE_FAILURE]) +
+			"，求值失败 " + strconv.Itoa(stats.Failures[pod.INDEX_ERR_EVALUATOR_FAILURE]) +
+			"，重复主键 " + strconv.Itoa(stats.Failures[pod.INDEX_ERR_DUPLICATE_KEY]))
+	}
+	for _, e := range result.Errors {
+		printRed("仓库索引失败: "+e.Error(), false)
+	}
 }
 
 // ** 前期数据 **
-func readExistKeys() (map[string]bool, error) {
-	var rows *sql.Rows
-	rows, e := _DB.Query(_SQL_QUERY_EXIST_KEY)
+func readExistRecords(ctx context.Context) (map[string]*store.Record, error) {
+	records, e := _Client.Store().ListIndex(ctx)
 	if e != nil {
 		return nil, e
 	}
+	m := make(map[string]*store.Record, len(records))
+	for _, r := range records {
+		m[r.Key] = r
+	}
+	return m, nil
+}
+
+// spec文件在入库后被修改，或已不存在(例如改名为.podspec.json)
+// 没有spec的版本Path为版本目录，目录仍不存在时视为未修改，spec下载后目录的修改时间晚于写入时间
+func specModified(r *store.Record) bool {
+	fi, e := os.Stat(r.Path)
+	if e != nil {
+		return isSpecPath(r.Path)
+	}
+	t := r.MTime
+	if t.IsZero() {
+		t = r.CTime
+	}
+	return fi.ModTime().After(t)
+}
 
-	m := make(map[string]bool)
-	for rows.Next() {
-		var key string
-		e = rows.Scan(&key)
-		if e != nil {
-			return nil, e
+func isSpecPath(p string) bool {
+	ext := path.Ext(p)
+	return ext == ".json" || ext == ".podspec"
+}
+
+// 本次遍历到且未被规则排除的版本，及因检查点跳过、未遍历其版本的模块，可被多个协程调用
+type syncWalked struct {
+	lock     sync.Mutex
+	versions map[string]bool
+	skipped  map[string]bool
+}
+
+func (s *syncWalked) addVersion(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.versions[key] = true
+}
+
+func (s *syncWalked) skipModule(module string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.skipped[module] = true
+}
+
+// 已配置仓库中本次未遍历到的版本，即版本已从仓库中删除或已被exclude/include规则排除
+// 遍历仓库出错时不调用，避免将未读取到的版本误判为已删除
+func removedRecords(existing map[string]*store.Record, repos []string, walked *syncWalked) []*store.Record {
+	res := make([]*store.Record, 0, 10)
+	for _, r := range existing {
+		if !pod.ContainsString(repos, r.Repo) || walked.skipped[r.Repo+"/"+r.Module] {
+			continue
 		}
-		if len(key) > 0 {
-			m[key] = true
+		if !walked.versions[r.Key] {
+			res = append(res, r)
 		}
 	}
-	return m, nil
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
 }
 
-func readExcluedModule() map[string]map[string]bool {
-	res := make(map[string]map[string]bool)
+// 每次同步重新生成各仓库的规则，规则中的计数仅统计本次索引；规则已在加载配置时校验
+func readRepoFilters() map[string]*repoFilter {
+	res := make(map[string]*repoFilter, len(_Conf.PodRepos))
 	for _, repo := range _Conf.PodRepos {
-		r, ok := res[repo.Name]
-		if !ok {
-			r = make(map[string]bool)
-			res[repo.Name] = r
+		if f, e := newRepoFilter(repo); e == nil {
+			res[repo.Name] = f
 		}
-		for _, m := range repo.Exclude {
-			r[m] = true
+	}
+	return res
+}
+
+func readExclusions(filters map[string]*repoFilter) []*OutputSyncExclusion {
+	res := make([]*OutputSyncExclusion, 0, 10)
+	for _, repo := range _Conf.PodRepos {
+		if f, ok := filters[repo.Name]; ok {
+			res = append(res, f.exclusions(repo.Name)...)
 		}
 	}
 	return res
 }
 
+func printExclusions(exclusions []*OutputSyncExclusion) {
+	if len(exclusions) == 0 {
+		return
+	}
+	println("规则排除统计：")
+	for _, x := range exclusions {
+		println("   " + x.Repo + " " + x.Rule + ": 模块 " + strconv.Itoa(x.Modules) + " 个，版本 " + strconv.Itoa(x.Versions) + " 个")
+	}
+}
+
+// 模块或版本目录所属的仓库名，master及CDN仓库的模块位于Specs下的子目录中
+func podPathRepo(p string) string {
+	rel := strings.TrimPrefix(p, path.Clean(_Conf.PodRepoRoot)+"/")
+	return strings.SplitN(rel, "/", 2)[0]
+}
+
 func readRepos() []string {
 	res := make([]string, 0, len(_Conf.PodRepos))
 	for _, repo := range _Conf.PodRepos {
@@ -106,82 +296,419 @@ func readRepos() []string {
 	return res
 }
 
-const __STR_DB_UNQ_ERR = `UNIQUE constraint failed:`
+// 各仓库当前的git HEAD
+func readRepoHeads() map[string]string {
+	res := make(map[string]string, len(_Conf.PodRepos))
+	for _, repo := range _Conf.PodRepos {
+		res[repo.Name] = repo.GitHead(_Conf.PodRepoRoot)
+	}
+	return res
+}
+
+// 写库期间持有_DBLock写锁，-serve模式下查询会等待正在写入的批次提交
+var _DBLock sync.RWMutex
+
+// 已提交的批次数，-serve模式下用于使同步过程中的缓存失效
+var _SyncBatches int64
+
+var errSyncCanceled = errors.New("同步已取消，已提交的批次保留，可执行 -sync --resume 继续")
+
+// 本次同步在updatelog中的记录，继续中断的同步时沿用原记录，耗时及各项计数在原记录上累加
+type syncSession struct {
+	log     *store.SyncLog
+	start   time.Time
+	elapsed time.Duration
+	resumed bool
+	heads   map[string]string
+}
+
+// 以status结束的同步记录，计数加上本批次的增量
+func (s *syncSession) finishedLog(status string, delta *store.SyncLog) *store.SyncLog {
+	l := *s.log
+	l.Status = status
+	l.Duration = s.elapsed + time.Since(s.start)
+	if delta != nil {
+		l.Added += delta.Added
+		l.Changed += delta.Changed
+		l.Removed += delta.Removed
+		l.Failed += delta.Failed
+	}
+	return &l
+}
 
-func syncToDB(p *pod.Pod) (int, int) {
-	tx, e := _DB.Begin()
+// 记录一次开始的同步并立即提交，进程异常退出时该记录保持running
+func startSyncSession(ctx context.Context) (*syncSession, error) {
+	_DBLock.Lock()
+	defer _DBLock.Unlock()
+	now := time.Now()
+	session := &syncSession{log: &store.SyncLog{Time: now, Version: _Version, ConfigHash: _Conf.Hash()}, start: now}
+	tx, e := _Client.Store().Begin(ctx)
 	if e != nil {
-		printRed(e.Error(), false)
-		return 0, 0
+		return nil, e
+	}
+	if session.log.ID, e = tx.StartSync(session.log); e != nil {
+		tx.Rollback()
+		return nil, e
+	}
+	return session, tx.Commit()
+}
+
+// 将同步记为中断，不受已取消的ctx影响
+func interruptSyncSession(session *syncSession) {
+	_DBLock.Lock()
+	defer _DBLock.Unlock()
+	tx, e := _Client.Store().Begin(context.Background())
+	if e == nil {
+		if e = tx.FinishSync(session.finishedLog(store.SYNC_STATUS_INTERRUPTED, nil)); e == nil {
+			e = tx.Commit()
+		} else {
+			tx.Rollback()
+		}
 	}
-	repoStmt, e := tx.Prepare(_SQL_INSERT_REPO)
 	if e != nil {
-		printRed(e.Error(), false)
-		return 0, 0
+		println("Warn: 记录同步中断失败->" + e.Error())
 	}
-	defer repoStmt.Close()
+}
 
-	logStmt, e := tx.Prepare(_SQLINSERT_LOG)
+// 最近一次同步未完成时返回该同步及已完成的模块(仓库/模块)，否则返回nil开始新的同步
+func readResumeSession(ctx context.Context) (*syncSession, map[string]bool, error) {
+	checkpoints := make(map[string]bool)
+	l, e := _Client.Store().LastSyncLog(ctx)
 	if e != nil {
-		printRed(e.Error(), false)
-		return 0, 0
+		return nil, nil, e
+	}
+	if l == nil || l.Status == store.SYNC_STATUS_COMPLETED {
+		println("没有中断的同步，开始新的同步...")
+		return nil, checkpoints, nil
+	}
+	list, e := _Client.Store().ListCheckpoints(ctx, l.ID)
+	if e != nil {
+		return nil, nil, e
+	}
+	for _, c := range list {
+		checkpoints[c.Repo+"/"+c.Module] = true
 	}
-	defer logStmt.Close()
+	println("继续 " + l.Time.Local().Format("2006-01-02 15:04:05") + " 开始的同步，已完成模块 " + strconv.Itoa(len(list)) + " 个")
+	return &syncSession{log: l, start: time.Now(), elapsed: l.Duration, resumed: true}, checkpoints, nil
+}
+
+// 解析失败的spec的Key及其所属的模块名
+func readRetryFailures(ctx context.Context) (map[string]bool, map[string]bool, error) {
+	failures, e := _Client.Store().ListFailures(ctx)
+	if e != nil {
+		return nil, nil, e
+	}
+	keys := make(map[string]bool, len(failures))
+	modules := make(map[string]bool)
+	for _, f := range failures {
+		keys[f.Key] = true
+		modules[f.Module] = true
+	}
+	println("重新解析失败的spec: " + strconv.Itoa(len(keys)) + " 个")
+	return keys, modules, nil
+}
 
-	currentTime := time.Now()
+// 一个提交批次：records为新增的版本，replaced为spec发生变化的版本，removed为版本目录已删除的记录
+// resolved为之前解析失败、本次解析成功的spec的Key
+type syncBatch struct {
+	records     []*store.Record
+	replaced    []*store.Record
+	touched     []*store.Record
+	removed     []*store.Record
+	failures    []*store.Failure
+	resolved    []string
+	checkpoints []*store.Checkpoint
+}
+
+func (s *syncBatch) size() int {
+	return len(s.records) + len(s.replaced) + len(s.touched) + len(s.failures)
+}
+
+func (s *syncBatch) reset() {
+	s.records = s.records[:0]
+	s.replaced = s.replaced[:0]
+	s.touched = s.touched[:0]
+	s.removed = s.removed[:0]
+	s.failures = s.failures[:0]
+	s.resolved = s.resolved[:0]
+	s.checkpoints = s.checkpoints[:0]
+}
+
+// 边解析边写入，每sync_batch_size条提交一次，模块的版本全部提交时同批记录检查点
+// 最后一批与移除的记录、同步完成状态及各仓库的统计一起提交；出错或ctx被取消时回滚当前批次，已提交的批次保留
+func syncToDB(ctx context.Context, p *pod.Pod, result *pod.IndexResult, session *syncSession, existing map[string]*store.Record, removed []*store.Record) (int, int, error) {
+	streamCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	progress := newSyncProgress(p)
+	// 模块剩余未写入批次的版本数，存在超时spec的模块不记录检查点
+	pending := make(map[string]int)
+	for _, repo := range p.PodRepos {
+		for _, module := range repo.Modules {
+			pending[repo.Name+"/"+module.Name] = len(module.Versions)
+		}
+	}
+	batch := &syncBatch{records: make([]*store.Record, 0, _Conf.SyncBatchSize)}
 	var suc, fail int
-	joinPod(p, func(k string, r string, m string, v string, p string, spec *pod.Spec) {
-		json := ""
-		if spec != nil {
-			if b, e := spec.JSON(); e == nil && b != nil {
-				json = string(b)
+	for item := range pod.PodStream(streamCtx, p, result, _Conf.SpecThread, _Conf.IsDebug()) {
+		progress.add(item.Repo)
+		key := item.Repo + "/" + item.Module
+		record := newSyncRecord(item, session.log.Time)
+		if e := item.Version.Err; e != nil {
+			println("Warn: 解析失败->" + record.Path + " 原因->" + e.Error())
+			if pod.IsEvaluateTimeout(e) {
+				// 超时的spec不入库，下次同步时重新求值
+				delete(pending, key)
+				continue
 			}
+			batch.failures = append(batch.failures, &store.Failure{
+				Key:      record.Key,
+				Repo:     record.Repo,
+				Module:   record.Module,
+				Version:  record.Version,
+				Path:     record.Path,
+				Category: pod.SpecErrorCategory(e),
+				Error:    e.Error(),
+				CTime:    time.Now(),
+			})
 		}
-		if _, e := repoStmt.Exec(k, r, m, v, p, json, currentTime); e != nil {
-			if strings.HasPrefix(e.Error(), __STR_DB_UNQ_ERR) {
-				println("Warn: 重复主键 { key: " + k + ", path: " + p + " }")
-				return
+		if old, ok := existing[record.Key]; !ok {
+			batch.records = append(batch.records, record)
+		} else if item.Version.Err == nil {
+			// 已索引的版本仅在spec内容变化时覆盖，重新解析失败时保留原记录
+			changed, e := specChanged(ctx, old, record)
+			if e != nil {
+				return suc, fail, syncBatchError(e, suc)
+			}
+			if changed {
+				// ctime记录版本首次入库的时间，spec变化时保留
+				record.CTime = old.CTime
+				batch.replaced = append(batch.replaced, record)
 			} else {
-				cp.Red(e.Error())
-				tx.Rollback()
-				os.Exit(1)
+				// 文件修改(例如重新clone或git checkout)但内容未变化，更新mtime避免之后每次同步都重新解析
+				batch.touched = append(batch.touched, record)
 			}
-			fail++
-		} else {
-			suc++
+			batch.resolved = append(batch.resolved, record.Key)
 		}
-	})
+		if n, ok := pending[key]; ok {
+			if n > 1 {
+				pending[key] = n - 1
+			} else {
+				delete(pending, key)
+				batch.checkpoints = append(batch.checkpoints, &store.Checkpoint{SyncID: session.log.ID, Repo: item.Repo, Module: item.Module})
+			}
+		}
+		if batch.size() < _Conf.SyncBatchSize {
+			continue
+		}
+		s, f, e := writeSyncBatch(ctx, session, batch, result, false)
+		suc, fail = suc+s, fail+f
+		if e != nil {
+			return suc, fail, syncBatchError(e, suc)
+		}
+		batch.reset()
+	}
+	if ctx.Err() != nil {
+		return suc, fail, syncBatchError(errSyncCanceled, suc)
+	}
+	progress.print()
+
+	batch.removed = removed
+	s, f, e := writeSyncBatch(ctx, session, batch, result, true)
+	suc, fail = suc+s, fail+f
+	if e != nil {
+		return suc, fail, syncBatchError(e, suc)
+	}
+	return suc, fail, nil
+}
+
+// 与已入库的spec JSON比较
+func specChanged(ctx context.Context, old *store.Record, r *store.Record) (bool, error) {
+	records, e := _Client.Store().GetSpecs(ctx, old.Module, old.Version)
+	if e != nil {
+		return false, e
+	}
+	for _, aRecord := range records {
+		if aRecord.Key == old.Key {
+			return aRecord.SpecJSON != r.SpecJSON, nil
+		}
+	}
+	return true, nil
+}
 
-	_, e = logStmt.Exec(currentTime)
+// 在一个事务中写入一批记录、模块版本的变化、解析失败及检查点；重复主键计入result
+// finish为true时一并记录同步完成状态及各仓库的统计，提交后将本批次的计数累加到session
+func writeSyncBatch(ctx context.Context, session *syncSession, batch *syncBatch, result *pod.IndexResult, finish bool) (int, int, error) {
+	_DBLock.Lock()
+	defer _DBLock.Unlock()
+	tx, e := _Client.Store().Begin(ctx)
+	if e != nil {
+		if ctx.Err() != nil {
+			return 0, 0, errSyncCanceled
+		}
+		return 0, 0, e
+	}
+	id := session.log.ID
+	delta := &store.SyncLog{Failed: len(batch.failures)}
+	var fail int
+	change := func(r *store.Record, action string) error {
+		return tx.RecordChange(&store.Change{SyncID: id, Repo: r.Repo, Module: r.Module, Version: r.Version, Action: action})
+	}
+	for _, r := range batch.records {
+		e = tx.PutSpec(r)
+		if e == store.ErrDuplicateKey {
+			println("Warn: 重复主键 { key: " + r.Key + ", path: " + r.Path + " }")
+			result.AddFailure(r.Repo, pod.INDEX_ERR_DUPLICATE_KEY)
+			fail++
+			e = nil
+			continue
+		}
+		if e == nil {
+			e = change(r, store.CHANGE_ADDED)
+		}
+		if e != nil {
+			break
+		}
+		delta.Added++
+	}
+	for _, r := range batch.replaced {
+		if e != nil {
+			break
+		}
+		if e = tx.ReplaceSpec(r); e == nil {
+			e = change(r, store.CHANGE_CHANGED)
+		}
+		delta.Changed++
+	}
+	for _, r := range batch.touched {
+		if e != nil {
+			break
+		}
+		e = tx.TouchSpec(r.Key, r.MTime)
+	}
+	for _, r := range batch.removed {
+		if e != nil {
+			break
+		}
+		if e = tx.DeleteSpec(r.Key); e == nil {
+			e = change(r, store.CHANGE_REMOVED)
+		}
+		delta.Removed++
+	}
+	for _, f := range batch.failures {
+		if e != nil {
+			break
+		}
+		e = tx.PutFailure(f)
+	}
+	for _, key := range batch.resolved {
+		if e != nil {
+			break
+		}
+		e = tx.DeleteFailure(key)
+	}
+	for _, c := range batch.checkpoints {
+		if e != nil {
+			break
+		}
+		e = tx.RecordCheckpoint(c)
+	}
+	if e == nil && finish {
+		e = finishSync(tx, session, result, delta)
+	}
+	if e == nil && ctx.Err() != nil {
+		e = errSyncCanceled
+	}
 	if e != nil {
-		printRed(e.Error(), false)
 		tx.Rollback()
-		return 0, 0
+		return 0, 0, e
+	}
+	if e = tx.Commit(); e != nil {
+		return 0, 0, e
+	}
+	atomic.AddInt64(&_SyncBatches, 1)
+	l := session.finishedLog(session.log.Status, delta)
+	l.Duration = session.log.Duration
+	if finish {
+		l.Status = store.SYNC_STATUS_COMPLETED
 	}
+	session.log = l
+	return delta.Added + delta.Changed, fail, nil
+}
 
-	tx.Commit()
-	return suc, fail
+func finishSync(tx store.Tx, session *syncSession, result *pod.IndexResult, delta *store.SyncLog) error {
+	if e := tx.FinishSync(session.finishedLog(store.SYNC_STATUS_COMPLETED, delta)); e != nil {
+		return e
+	}
+	for _, stats := range result.Repos {
+		e := tx.RecordRepoStats(&store.RepoStats{
+			SyncID:           session.log.ID,
+			SyncTime:         session.log.Time,
+			Repo:             stats.Name,
+			Success:          stats.Success,
+			MissingSpec:      stats.Failures[pod.INDEX_ERR_MISSING_SPEC],
+			ParseFailure:     stats.Failures[pod.INDEX_ERR_PARSE_FAILURE],
+			EvaluatorFailure: stats.Failures[pod.INDEX_ERR_EVALUATOR_FAILURE],
+			DuplicateKey:     stats.Failures[pod.INDEX_ERR_DUPLICATE_KEY],
+			GitHead:          session.heads[stats.Name],
+		})
+		if e != nil {
+			return e
+		}
+	}
+	return nil
 }
 
-type funcJoinPodCallback func(k string, r string, m string, v string, p string, spec *pod.Spec)
+// 同步中断时提示已提交的条数
+func syncBatchError(e error, committed int) error {
+	if committed == 0 {
+		return e
+	}
+	return errors.New(e.Error() + "（已提交 " + strconv.Itoa(committed) + " 条）")
+}
 
-func joinPod(p *pod.Pod, f funcJoinPodCallback) {
-	if p == nil || f == nil {
-		return
+// 版本目录的MD5作为Key，解析失败的版本spec_json为空
+func newSyncRecord(item *pod.IndexedSpec, t time.Time) *store.Record {
+	v := item.Version
+	json := ""
+	if item.Spec != nil {
+		if b, e := item.Spec.JSON(); e == nil && b != nil {
+			json = string(b)
+		}
 	}
+	return &store.Record{
+		Key:      str.MD5(v.Root),
+		Repo:     item.Repo,
+		Module:   item.Module,
+		Version:  v.Name,
+		Path:     path.Join(v.Root, v.FileName),
+		SpecJSON: json,
+		CTime:    t,
+		MTime:    t,
+	}
+}
+
+// 求值超时的spec路径
+func podTimeoutSpecs(p *pod.Pod) []string {
+	res := make([]string, 0, 10)
 	for _, repo := range p.PodRepos {
 		for _, module := range repo.Modules {
 			for _, version := range module.Versions {
-				key := str.MD5(version.Root)
-				specPath := path.Join(version.Root, version.FileName)
-				var spec *pod.Spec
-				if version.Err != nil {
-					println("Warn: 解析失败->" + specPath + " 原因->" + version.Err.Error())
-				} else {
-					spec = version.Podspec
+				if pod.IsEvaluateTimeout(version.Err) {
+					res = append(res, path.Join(version.Root, version.FileName))
 				}
-				f(key, repo.Name, module.Name, version.Name, specPath, spec)
 			}
 		}
 	}
+	return res
+}
+
+func printTimeoutSpecs(paths []string) {
+	if len(paths) == 0 {
+		return
+	}
+	sort.Strings(paths)
+	printRed("求值超时的spec: "+strconv.Itoa(len(paths))+" 个", false)
+	for _, p := range paths {
+		println("   - " + p)
+	}
 }
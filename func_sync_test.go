@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"os"
+	"pandora/client"
+	"pandora/store"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// 使用内存存储及临时仓库根目录，仓库r中包含模块A的1.0版本
+func setupSyncTest(t *testing.T) string {
+	root := t.TempDir()
+	spec := filepath.Join(root, "r", "A", "1.0", "A.podspec.json")
+	if e := os.MkdirAll(filepath.Dir(spec), 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.WriteFile(spec, []byte(`{"name": "A", "version": "1.0"}`), 0644); e != nil {
+		t.Fatal(e)
+	}
+	_Conf = &Config{Workspace: t.TempDir(), PodRepoRoot: root, PodRepos: []*ConfigRepo{{Name: "r"}}}
+	if e := _Conf.Check(); e != nil {
+		t.Fatal(e)
+	}
+	_Client = client.New(_Conf.ClientConfig(), store.NewMemory())
+	_Ctx = context.Background()
+	return spec
+}
+
+func mustSync(t *testing.T) *OutputSync {
+	t.Helper()
+	out, e := runSync(_Ctx, __SYNC_MODE_NORMAL)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return out
+}
+
+// spec文件修改时间变化但内容未变化时，同步后更新mtime，之后的同步不再重新解析
+func TestSyncTouchedSpec(t *testing.T) {
+	spec := setupSyncTest(t)
+	if out := mustSync(t); out.Added != 1 {
+		t.Fatalf("首次同步新增 %d 个", out.Added)
+	}
+
+	now := time.Now()
+	if e := os.Chtimes(spec, now, now); e != nil {
+		t.Fatal(e)
+	}
+	out := mustSync(t)
+	if out.Success != 1 || out.Changed != 0 {
+		t.Fatalf("修改时间变化后应重新解析且内容未变化: 解析 %d 变化 %d", out.Success, out.Changed)
+	}
+	if out = mustSync(t); out.Success != 0 {
+		t.Fatalf("内容未变化的spec被再次解析: %d", out.Success)
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"io/ioutil"
 	"pandora/pod"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -19,7 +23,7 @@ func cmd_upgrade(aArgs *Args) {
 		outType = r
 	}
 	if len(joinArgs) < 1 {
-		exitWithMessage("参数错误！", true)
+		exitWithOutputError("参数错误！", true)
 	}
 	for i := 0; i < len(joinArgs); i++ {
 		p := joinArgs[i]
@@ -31,40 +35,137 @@ func cmd_upgrade(aArgs *Args) {
 	var err error
 	if flag != "" {
 		printGreen("开始解析Podfile: "+flag, false)
-		upPodfile, err = pod.NewPodfile(flag, true)
+		upPodfile, err = pod.NewPodfile(_Ctx, flag, true)
 		if err != nil {
-			exitWithMessage(err.Error(), true)
+			exitWithOutputError(err.Error(), true)
 		}
-		pod.FillPodfile(upPodfile, _Conf.SpecThread, true)
+		pod.FillPodfile(_Ctx, upPodfile, _Conf.SpecThread, true)
 	}
 
 	joinPodfiles := make([]*pod.Podfile, 0, len(joinArgs))
 	for _, pf := range joinArgs {
 		printGreen("开始解析Podfile: "+pf, false)
-		aPodfile, err := pod.NewPodfile(pf, true)
+		aPodfile, err := pod.NewPodfile(_Ctx, pf, true)
 		if err != nil {
-			exitWithMessage(err.Error(), true)
+			exitWithOutputError(err.Error(), true)
 		}
-		pod.FillPodfile(aPodfile, _Conf.SpecThread, true)
+		pod.FillPodfile(_Ctx, aPodfile, _Conf.SpecThread, true)
 		joinPodfiles = append(joinPodfiles, aPodfile)
 	}
+	if _Ctx.Err() != nil {
+		exitWithOutputError("已取消！", false)
+	}
 
+	ctx := _Ctx
+	printSpecCacheStats()
+	printTimeoutSpecs(podfileTimeoutSpecs(upPodfile, joinPodfiles...))
 	graphPodfiles := make([]pod.GraphPodfile, 0, len(joinPodfiles))
+	podfileRepos := make([][]string, 0, len(joinPodfiles))
 	for _, pf := range joinPodfiles {
 		printGreen("开始分析Podfile: "+pf.FilePath, false)
-		aGraphPodfile := buildGraphPodfiles(pf, upPodfile)
+		repos := resolvePodfileRepos(pf)
+		podfileRepos = append(podfileRepos, repos)
+		aGraphPodfile := buildGraphPodfiles(ctx, pf, upPodfile, repos)
 		graphPodfiles = append(graphPodfiles, aGraphPodfile)
 	}
 	printGreen("开始提取公共依赖 ...", false)
 	intersection(graphPodfiles...)
 
+	withTargets := aArgs.CheckSubargs("--targets")
+	targetDiff := aArgs.GetSubargs("--target_diff")
+	if aArgs.CheckSubargs("--target_diff") && len(targetDiff) != 2 {
+		exitWithOutputError("--target_diff 需要两个target名称", true)
+	}
+	targetGraphs := make([]map[string]pod.GraphPodfile, len(joinPodfiles))
+	if withTargets || len(targetDiff) > 0 {
+		for idx, pf := range joinPodfiles {
+			printGreen("开始分析Target: "+pf.FilePath, false)
+			targetGraphs[idx] = buildGraphTargets(ctx, pf, upPodfile, podfileRepos[idx])
+			markCommon(targetGraphs[idx], graphPodfiles[idx])
+		}
+	}
+
 	printGreen("开始输出文件 ...", false)
+	exportOpt := readGraphExportOption(aArgs)
 	date := time.Now()
+	out := &OutputUpgrade{Podfiles: make([]*OutputUpgradeGraph, 0, len(graphPodfiles))}
 	for idx, aGP := range graphPodfiles {
 		aPF := joinPodfiles[idx]
 		fp := generateWritePath(aPF.FilePath, &date)
 		writeGraphPodfile(fp, aGP, outType)
+		writeMarkdownSummary(ctx, fp, aPF, aGP, podfileRepos[idx], date)
+		for _, f := range _GraphExportFormats {
+			if !aArgs.CheckSubargs(f.Option) {
+				continue
+			}
+			writeGraphExport(fp, aGP, exportOpt, f.Format, f.Ext)
+			for name, aTGP := range targetGraphs[idx] {
+				writeGraphExport(path.Join(fp+"_targets", targetFileName(name)), aTGP, exportOpt, f.Format, f.Ext)
+			}
+		}
+		out.Output = path.Dir(fp)
+		repos := podfileRepos[idx]
+		if repos == nil {
+			repos = []string{}
+		}
+		out.Podfiles = append(out.Podfiles, &OutputUpgradeGraph{
+			Path:    aPF.FilePath,
+			Repos:   repos,
+			Modules: newOutputGraph(aGP),
+			Targets: newOutputTargets(targetGraphs[idx]),
+		})
+		if withTargets {
+			writeGraphTargets(fp, targetGraphs[idx])
+		}
+		if len(targetDiff) == 2 {
+			printTargetDiff(aPF.FilePath, targetGraphs[idx], targetDiff[0], targetDiff[1])
+		}
+	}
+	if out.Output != "" {
+		writeHTMLReport(out.Output, date, out.Podfiles)
+	}
+
+	if isJSONOutput() {
+		defer printJSON(out)
+	}
+
+	if !aArgs.CheckSubargs("--apply") {
+		return
+	}
+	applyDir := aArgs.GetFirstSubArgs("--apply")
+	if applyDir != "" {
+		applyDir = absolutePath(applyDir)
 	}
+	withImplicit := aArgs.CheckSubargs("--implicit")
+	// 原地改写Podfile需要--yes确认，否则仅输出diff预览
+	unconfirmed := applyDir == "" && !aArgs.CheckSubargs("--yes")
+	dryRun := aArgs.CheckSubargs("--dry_run") || unconfirmed
+	for idx, aGP := range graphPodfiles {
+		applyGraphPodfile(joinPodfiles[idx], aGP, applyDir, withImplicit, dryRun)
+	}
+	if unconfirmed && !aArgs.CheckSubargs("--dry_run") {
+		println("仅输出预览，确认后添加 --yes 原地改写Podfile")
+	}
+}
+
+// Podfile中本地模块求值超时的spec路径
+func podfileTimeoutSpecs(upPodfile *pod.Podfile, podfiles ...*pod.Podfile) []string {
+	if upPodfile != nil {
+		podfiles = append(podfiles, upPodfile)
+	}
+	dup := make(map[string]bool)
+	res := make([]string, 0, 10)
+	for _, aPodfile := range podfiles {
+		for _, aTarget := range aPodfile.Targets {
+			for _, aDep := range aTarget.Depends {
+				if pod.IsEvaluateTimeout(aDep.Err) && !dup[aDep.SpecPath] {
+					dup[aDep.SpecPath] = true
+					res = append(res, aDep.SpecPath)
+				}
+			}
+		}
+	}
+	return res
 }
 
 func intersection(graphPodfiles ...pod.GraphPodfile) {
@@ -97,74 +198,83 @@ func intersection(graphPodfiles ...pod.GraphPodfile) {
 	}
 }
 
-func buildGraphPodfiles(podfile *pod.Podfile, upPodfile *pod.Podfile) pod.GraphPodfile {
-	graphPodfile := make(pod.GraphPodfile)
+// 根据Podfile中的source确定查询的仓库，与CocoaPods一致按source的声明顺序，不使用仓库priority；
+// 未声明source或无法匹配时查询全部仓库并按priority排序
+func resolvePodfileRepos(podfile *pod.Podfile) []string {
+	if len(podfile.Sources) == 0 {
+		return nil
+	}
+	repos, unmatched := _Conf.ReposForSources(podfile.Sources)
+	for _, source := range unmatched {
+		printRed("Warn: source未匹配到已配置的仓库 -> "+source, false)
+	}
+	if len(repos) == 0 {
+		return nil
+	}
+	println("使用仓库: " + strings.Join(repos, " > "))
+	return repos
+}
+
+func buildGraphPodfiles(ctx context.Context, podfile *pod.Podfile, upPodfile *pod.Podfile, repos []string) pod.GraphPodfile {
+	deps := make([]*pod.Depend, 0, 50)
 	for _, aTarget := range podfile.Targets {
-		for _, aDep := range aTarget.Depends {
-			_, ok := graphPodfile[aDep.Name()]
-			if ok {
-				continue
-			}
-			aModule := buildGraphModule(aDep, upPodfile)
-			graphPodfile[aModule.Name] = aModule
+		deps = append(deps, aTarget.Depends...)
+	}
+	return buildGraphDepends(ctx, podfile.FilePath, deps, upPodfile, repos)
+}
+
+// 按target分别构建依赖图，子target按继承方式合并父target的依赖
+func buildGraphTargets(ctx context.Context, podfile *pod.Podfile, upPodfile *pod.Podfile, repos []string) map[string]pod.GraphPodfile {
+	res := make(map[string]pod.GraphPodfile)
+	for _, aTarget := range podfile.ConcreteTargets() {
+		res[aTarget.Name] = buildGraphDepends(ctx, podfile.FilePath+" ["+aTarget.Name+"]", aTarget.EffectiveDepends(), upPodfile, repos)
+	}
+	return res
+}
+
+func buildGraphDepends(ctx context.Context, label string, deps []*pod.Depend, upPodfile *pod.Podfile, repos []string) pod.GraphPodfile {
+	graphPodfile := make(pod.GraphPodfile)
+	for _, aDep := range deps {
+		_, ok := graphPodfile[aDep.Name()]
+		if ok {
+			continue
 		}
+		aModule := buildGraphModule(ctx, aDep, upPodfile, repos)
+		graphPodfile[aModule.Name] = aModule
 	}
-	check(podfile.FilePath, graphPodfile, upPodfile, 1)
+	check(ctx, label, graphPodfile, upPodfile, repos, 1)
 	return graphPodfile
 }
 
-func buildGraphModule(aDepend pod.IDepend, upPodfile *pod.Podfile) *pod.GraphModule {
-	graphModule := new(pod.GraphModule)
-	var upVersion, newest string
-	if upPodfile != nil {
-		var exist bool
-		upVersion, exist = upPodfile.GetDependVersion(nil, aDepend.Name())
-		if exist {
-			upVersion = realVesion(aDepend.Name(), upVersion)
-		}
+func buildGraphModule(ctx context.Context, aDepend pod.IDepend, upPodfile *pod.Podfile, repos []string) *pod.GraphModule {
+	if source := aDepend.ExternalSource(); source != nil && !aDepend.IsLocal() {
+		println("外部来源模块(不升级): " + aDepend.Name() + " [" + source.String() + "]")
 	}
-	newest, _ = queryNewestVersion(aDepend.Name(), "")
-	if pod.IsVersion(aDepend.Version()) {
-		graphModule.Version = aDepend.Version()
-	} else if aDepend.Version() == "" {
-		graphModule.Version = newest
-	} else {
-		graphModule.Version, _ = queryNewestVersion(aDepend.Name(), aDepend.Version())
-	}
-	graphModule.Name = aDepend.Name()
-	graphModule.UpdateToVersion = upVersion
-	graphModule.NewestVersion = newest
-	graphModule.IsLocal = aDepend.IsLocal()
-	if aDepend.Subdepends() != nil && graphModule.UseVersion() == graphModule.Version {
-		graphModule.Depends = aDepend.Subdepends()
-	} else if graphModule.Version != "" {
-		deps, _, e := queryDepends(graphModule.Name, graphModule.UseVersion())
-		if e == nil && len(deps) > 0 {
-			graphModule.Depends = deps
-		}
+	graphModule, e := _Client.GraphModule(ctx, aDepend, upPodfile, repos)
+	if e != nil {
+		printRed("查询模块错误["+aDepend.Name()+"]: "+e.Error(), false)
+		return &pod.GraphModule{Name: aDepend.Name(), IsLocal: aDepend.IsLocal(), Source: aDepend.ExternalSource()}
 	}
 	return graphModule
 }
 
-func realVesion(module string, version string) string {
-	if pod.IsVersion(version) {
-		return version
-	}
-	if version == "" {
-		v, e := queryNewestVersion(module, "")
-		if e != nil {
-			return ""
+// 以合并后的依赖图为准标记各target中的公共依赖
+func markCommon(targetGraphs map[string]pod.GraphPodfile, union pod.GraphPodfile) {
+	for _, aGP := range targetGraphs {
+		for name, aModule := range aGP {
+			if u, ok := union[name]; ok {
+				aModule.IsCommon = u.IsCommon
+			}
 		}
-		return v
-	}
-	v, e := queryNewestVersion(module, version)
-	if e != nil {
-		return ""
 	}
-	return v
 }
 
-func check(filePath string, graphPodfile pod.GraphPodfile, upPodfile *pod.Podfile, times int) {
+// 隐性依赖分析的最大迭代次数，避免相互冲突的约束使升级版本来回变化
+const __CHECK_MAX_TIMES = 50
+
+// 迭代补全隐性依赖并升级不满足约束的模块，某次迭代没有任何模块变化时停止，
+// 例如:git/:path等外部来源的模块不满足其他模块的约束，剩余的约束作为警告输出
+func check(ctx context.Context, filePath string, graphPodfile pod.GraphPodfile, upPodfile *pod.Podfile, repos []string, times int) {
 	println("分析隐性依赖[第" + strconv.Itoa(times) + "次迭代]: " + filePath)
 	if graphPodfile == nil {
 		return
@@ -174,6 +284,7 @@ func check(filePath string, graphPodfile pod.GraphPodfile, upPodfile *pod.Podfil
 		return
 	}
 	var buffer bytes.Buffer
+	updated := false
 	for _, aDep := range unfound {
 		if _Conf.IsDebug() {
 			buffer.WriteString("[")
@@ -184,27 +295,42 @@ func check(filePath string, graphPodfile pod.GraphPodfile, upPodfile *pod.Podfil
 			buffer.WriteString("] ")
 		}
 		old, ok := graphPodfile[aDep.Name()]
+		if ok && old.IsExternal() {
+			continue
+		}
 		if ok {
-			v, e := queryNewestVersion(aDep.Name(), aDep.Version())
+			v, e := _Client.NewestVersion(ctx, aDep.Name(), aDep.Version(), repos...)
 			if e != nil || v == "" {
-				old.UpdateToVersion = "*"
-			} else {
-				old.UpdateToVersion = v
-				deps, _, e := queryDepends(old.Name, old.UseVersion())
-				if e == nil {
-					old.Depends = deps
+				v = "*"
+			}
+			if v == old.UpdateToVersion {
+				continue
+			}
+			updated = true
+			if old.UpdateToVersion = v; v != "*" {
+				if res, e := _Client.Depends(ctx, old.Name, old.UseVersion(), repos...); e == nil {
+					old.Depends = res.Depends
 				}
 			}
 		} else {
-			aModule := buildGraphModule(aDep, upPodfile)
+			aModule := buildGraphModule(ctx, aDep, upPodfile, repos)
 			aModule.IsNew = true
+			if _, exist := graphPodfile[aModule.Name]; !exist {
+				updated = true
+			}
 			graphPodfile[aModule.Name] = aModule
 		}
 	}
 	if _Conf.IsDebug() {
 		println(buffer.String())
 	}
-	check(filePath, graphPodfile, upPodfile, times+1)
+	if !updated || times >= __CHECK_MAX_TIMES {
+		for _, aDep := range unfound {
+			printYellow("Warn: 无法满足的依赖约束 "+aDep.Name()+" "+aDep.Version()+" ["+filePath+"]", false)
+		}
+		return
+	}
+	check(ctx, filePath, graphPodfile, upPodfile, repos, times+1)
 }
 
 func generateWritePath(filePath string, date *time.Time) string {
@@ -232,20 +358,7 @@ func writeGraphPodfile(filePath string, graphPodfile pod.GraphPodfile, outputMod
 	if ext != ".csv" {
 		csvFilePath += ".csv"
 	}
-	var buffer bytes.Buffer
-	if _, e := buffer.WriteString("ModuleName,IsCommon,IsImplicit,IsLocal,Current,UpgradeTo,UpgradeTag,Newest,Dependencies\n"); e != nil {
-		printRed(e.Error(), false)
-		return
-	}
-	if graphPodfile != nil {
-		if _, e := buffer.Write(graphPodfile.Bytes()); e != nil {
-			printRed(e.Error(), false)
-			return
-		}
-	}
-	if e := WriteFile(csvFilePath, buffer.Bytes(), true, os.ModePerm); e != nil {
-		printRed("输出文件错误["+csvFilePath+"]: "+e.Error(), false)
-	}
+	writeGraphCSV(csvFilePath, graphPodfile)
 
 	// 是否输出辅助文件
 	if outputMode == 0 {
@@ -262,6 +375,10 @@ func writeGraphPodfile(filePath string, graphPodfile pod.GraphPodfile, outputMod
 	}
 	for _, aModule := range graphPodfile {
 		if outputMode&__OPTION_OUTPUT_COMMON == __OPTION_OUTPUT_COMMON && aModule.IsCommon {
+			if aModule.IsExternal() {
+				bufferCommon.WriteString("pod '" + aModule.Name + "', " + aModule.Source.PodfileOptions() + " # External\n")
+				continue
+			}
 			note := ""
 			if aModule.IsLocal {
 				note = " # Local"
@@ -269,7 +386,7 @@ func writeGraphPodfile(filePath string, graphPodfile pod.GraphPodfile, outputMod
 			bufferCommon.WriteString("pod '" + aModule.Name + "', '" + aModule.UseVersion() + "'" + note + "\n")
 			continue
 		}
-		if outputMode&__OPTION_OUTPUT_REMOTE == __OPTION_OUTPUT_REMOTE && !aModule.IsCommon && !aModule.IsLocal {
+		if outputMode&__OPTION_OUTPUT_REMOTE == __OPTION_OUTPUT_REMOTE && !aModule.IsCommon && !aModule.IsLocal && !aModule.IsExternal() {
 			bufferRemote.WriteString("pod '" + aModule.Name + "', '" + aModule.UseVersion() + "'\n")
 		}
 	}
@@ -287,6 +404,160 @@ func writeGraphPodfile(filePath string, graphPodfile pod.GraphPodfile, outputMod
 	}
 }
 
+const __CSV_GRAPH_HEADER = "ModuleName,IsCommon,IsImplicit,IsLocal,Current,UpgradeTo,UpgradeTag,Newest,External,Dependencies\n"
+
+func writeGraphCSV(csvFilePath string, graphPodfile pod.GraphPodfile) {
+	var buffer bytes.Buffer
+	buffer.WriteString(__CSV_GRAPH_HEADER)
+	if graphPodfile != nil {
+		buffer.Write(graphPodfile.Bytes())
+	}
+	if e := WriteFile(csvFilePath, buffer.Bytes(), true, os.ModePerm); e != nil {
+		printRed("输出文件错误["+csvFilePath+"]: "+e.Error(), false)
+	}
+}
+
+// 输出每个target的依赖图，以及各target间存在差异的模块版本矩阵
+func writeGraphTargets(filePath string, targetGraphs map[string]pod.GraphPodfile) {
+	if len(targetGraphs) == 0 {
+		return
+	}
+	names := make([]string, 0, len(targetGraphs))
+	for name := range targetGraphs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	dir := filePath + "_targets"
+	modules := make(map[string]bool)
+	for _, name := range names {
+		aGP := targetGraphs[name]
+		writeGraphCSV(path.Join(dir, targetFileName(name)+".csv"), aGP)
+		for m := range aGP {
+			modules[m] = true
+		}
+	}
+
+	sortedModules := make([]string, 0, len(modules))
+	for m := range modules {
+		sortedModules = append(sortedModules, m)
+	}
+	sort.Strings(sortedModules)
+
+	var buffer bytes.Buffer
+	buffer.WriteString("ModuleName," + strings.Join(names, ",") + "\n")
+	for _, m := range sortedModules {
+		versions := make([]string, 0, len(names))
+		differ := false
+		for idx, name := range names {
+			v := ""
+			if aModule, ok := targetGraphs[name][m]; ok {
+				v = aModule.UseVersion()
+				if v == "" {
+					v = "*"
+				}
+			}
+			if idx > 0 && v != versions[0] {
+				differ = true
+			}
+			versions = append(versions, v)
+		}
+		if differ {
+			buffer.WriteString(m + "," + strings.Join(versions, ",") + "\n")
+		}
+	}
+	csvFilePath := path.Join(dir, "diff.csv")
+	if e := WriteFile(csvFilePath, buffer.Bytes(), true, os.ModePerm); e != nil {
+		printRed("输出文件错误["+csvFilePath+"]: "+e.Error(), false)
+	}
+}
+
+func printTargetDiff(podfilePath string, targetGraphs map[string]pod.GraphPodfile, a string, b string) {
+	aGP, okA := targetGraphs[a]
+	bGP, okB := targetGraphs[b]
+	if !okA || !okB {
+		printRed("Target不存在: "+a+" / "+b+" ["+podfilePath+"]", false)
+		return
+	}
+	println("-> Target差异: " + a + " <-> " + b + " [" + podfilePath + "]")
+	diffs := aGP.Diff(bGP)
+	if len(diffs) == 0 {
+		println("   无差异")
+		return
+	}
+	for _, d := range diffs {
+		va, vb := "-", "-"
+		if d.A != nil {
+			va = d.A.UseVersion()
+		}
+		if d.B != nil {
+			vb = d.B.UseVersion()
+		}
+		println("   - " + d.Name + "  " + va + " | " + vb)
+	}
+}
+
+func targetFileName(name string) string {
+	return strings.Replace(name, "/", "_", -1)
+}
+
+// 将分析结果写回Podfile，仅改写受影响pod行的版本要求；outDir为空时原地改写
+func applyGraphPodfile(aPodfile *pod.Podfile, graphPodfile pod.GraphPodfile, outDir string, withImplicit bool, dryRun bool) {
+	versions := make(map[string]string)
+	implicits := make([]*pod.DependBase, 0, 10)
+	for _, aModule := range graphPodfile {
+		if aModule.IsLocal || aModule.IsExternal() {
+			continue
+		}
+		v := aModule.UseVersion()
+		if v == "" || v == "*" {
+			continue
+		}
+		if aModule.IsNew {
+			if withImplicit {
+				implicits = append(implicits, &pod.DependBase{N: aModule.Name, V: v})
+			}
+			continue
+		}
+		if v != aModule.Version {
+			versions[aModule.Name] = v
+		}
+	}
+
+	original, e := ioutil.ReadFile(aPodfile.FilePath)
+	if e != nil {
+		printRed("读取Podfile错误["+aPodfile.FilePath+"]: "+e.Error(), false)
+		return
+	}
+	b, changed, skipped, e := aPodfile.Rewrite(versions, implicits)
+	if e != nil {
+		printRed("改写Podfile错误["+aPodfile.FilePath+"]: "+e.Error(), false)
+		return
+	}
+	for _, aSkip := range skipped {
+		printYellow("Warn: 未改写 "+aSkip.Name+" ("+aSkip.Reason+")，请手动修改为 "+aSkip.Version, false)
+	}
+	target := aPodfile.FilePath
+	if outDir != "" {
+		target = path.Join(outDir, podfileName(aPodfile.FilePath))
+	}
+	diff := UnifiedDiff(aPodfile.FilePath, original, target, b)
+	if diff == "" {
+		println("Podfile无需改写: " + aPodfile.FilePath)
+		return
+	}
+	printGreen("Podfile改写预览: "+aPodfile.FilePath, false)
+	printDiff(diff)
+	if dryRun {
+		return
+	}
+	if e := WriteFile(target, b, true, 0644); e != nil {
+		printRed("输出文件错误["+target+"]: "+e.Error(), false)
+		return
+	}
+	println("已改写 " + strconv.Itoa(len(changed)) + " 个模块: " + target)
+}
+
 func podfileName(p string) string {
 	n := path.Base(p)
 	for index := 0; index < 2; index++ {
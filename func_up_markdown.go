@@ -0,0 +1,171 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"pandora/pod"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 条目超过该数量时折叠章节
+const __MD_FOLD_THRESHOLD = 10
+
+type mdEntry struct {
+	Name    string
+	Version string
+	Deps    string
+}
+
+// 输出Markdown格式的升级摘要，便于直接粘贴到合并请求描述中
+func writeMarkdownSummary(ctx context.Context, filePath string, podfile *pod.Podfile, graphPodfile pod.GraphPodfile, repos []string, date time.Time) {
+	declared := make(map[string]bool)
+	for _, aTarget := range podfile.Targets {
+		for _, aDep := range aTarget.Depends {
+			declared[aDep.Name()] = true
+		}
+	}
+
+	var ups, downs, implicits, skipped, removed []*mdEntry
+	oldRequired := make(map[string]string)
+	newRequired := make(map[string]bool)
+	for _, name := range graphPodfile.SortedNames() {
+		m := graphPodfile[name]
+		for _, aDep := range m.Depends {
+			newRequired[pod.BaseModule(aDep.N)] = true
+		}
+		if m.IsLocal || m.IsExternal() {
+			note := "本地"
+			if m.IsExternal() {
+				note = m.External()
+			}
+			skipped = append(skipped, &mdEntry{Name: m.Name, Version: m.UseVersion(), Deps: note})
+			continue
+		}
+		if m.IsNew {
+			implicits = append(implicits, &mdEntry{Name: m.Name, Version: m.UseVersion(), Deps: formatDepends(m.Depends)})
+			continue
+		}
+		tag := m.UpgradeTag()
+		if tag != "up" && tag != "down" {
+			continue
+		}
+		var oldDeps []*pod.DependBase
+		if res, e := _Client.Depends(ctx, m.Name, m.Version, repos...); e == nil {
+			oldDeps = res.Depends
+		}
+		for _, aDep := range oldDeps {
+			oldRequired[pod.BaseModule(aDep.N)] = m.Name
+		}
+		entry := &mdEntry{Name: m.Name, Version: m.Version + " → " + m.UseVersion(), Deps: diffDepends(oldDeps, m.Depends)}
+		if tag == "up" {
+			ups = append(ups, entry)
+		} else {
+			downs = append(downs, entry)
+		}
+	}
+	for name, by := range oldRequired {
+		if newRequired[name] || declared[name] || pod.BaseModule(by) == name {
+			continue
+		}
+		if _, ok := graphPodfile[name]; ok {
+			continue
+		}
+		removed = append(removed, &mdEntry{Name: name, Version: "-", Deps: "不再被 " + by + " 依赖"})
+	}
+	sort.Slice(removed, func(i, j int) bool {
+		return removed[i].Name < removed[j].Name
+	})
+
+	var buffer bytes.Buffer
+	buffer.WriteString("# 升级摘要: " + podfileName(podfile.FilePath) + "\n\n")
+	buffer.WriteString("> Podfile: `" + podfile.FilePath + "`  \n")
+	buffer.WriteString("> 生成时间: " + date.Format("2006-01-02 15:04:05") + "\n\n")
+	writeMarkdownSection(&buffer, "升级", ups)
+	writeMarkdownSection(&buffer, "降级", downs)
+	writeMarkdownSection(&buffer, "新增隐性依赖", implicits)
+	writeMarkdownSection(&buffer, "移除", removed)
+	writeMarkdownSection(&buffer, "跳过的本地/外部模块", skipped)
+
+	p := filePath + ".md"
+	if e := WriteFile(p, buffer.Bytes(), true, os.ModePerm); e != nil {
+		printRed("输出文件错误["+p+"]: "+e.Error(), false)
+	}
+}
+
+func writeMarkdownSection(buffer *bytes.Buffer, title string, entries []*mdEntry) {
+	buffer.WriteString("## " + title + " (" + strconv.Itoa(len(entries)) + ")\n\n")
+	if len(entries) == 0 {
+		buffer.WriteString("无\n\n")
+		return
+	}
+	fold := len(entries) > __MD_FOLD_THRESHOLD
+	if fold {
+		buffer.WriteString("<details>\n<summary>展开 " + strconv.Itoa(len(entries)) + " 项</summary>\n\n")
+	}
+	buffer.WriteString("| 模块 | 版本 | 依赖变化 |\n| --- | --- | --- |\n")
+	for _, entry := range entries {
+		buffer.WriteString("| " + markdownEscape(entry.Name) + " | " + markdownEscape(entry.Version) + " | " + markdownEscape(entry.Deps) + " |\n")
+	}
+	if fold {
+		buffer.WriteString("\n</details>\n")
+	}
+	buffer.WriteString("\n")
+}
+
+// 比较新旧版本的依赖，返回新增(+)、移除(-)及版本要求变化
+func diffDepends(oldDeps []*pod.DependBase, newDeps []*pod.DependBase) string {
+	oldMap := make(map[string]string)
+	for _, aDep := range oldDeps {
+		oldMap[aDep.N] = aDep.V
+	}
+	newMap := make(map[string]string)
+	for _, aDep := range newDeps {
+		newMap[aDep.N] = aDep.V
+	}
+	changes := make([]string, 0, 5)
+	for name, v := range newMap {
+		old, ok := oldMap[name]
+		if !ok {
+			changes = append(changes, strings.TrimSpace("+"+name+" "+v))
+		} else if old != v {
+			changes = append(changes, name+": "+requirementString(old)+" → "+requirementString(v))
+		}
+	}
+	for name := range oldMap {
+		if _, ok := newMap[name]; !ok {
+			changes = append(changes, "-"+name)
+		}
+	}
+	if len(changes) == 0 {
+		return "无变化"
+	}
+	sort.Strings(changes)
+	return strings.Join(changes, "; ")
+}
+
+func formatDepends(deps []*pod.DependBase) string {
+	if len(deps) == 0 {
+		return "-"
+	}
+	res := make([]string, 0, len(deps))
+	for _, aDep := range deps {
+		res = append(res, strings.TrimSpace(aDep.N+" "+aDep.V))
+	}
+	sort.Strings(res)
+	return strings.Join(res, "; ")
+}
+
+func requirementString(v string) string {
+	if v == "" {
+		return "*"
+	}
+	return v
+}
+
+func markdownEscape(s string) string {
+	return strings.Replace(s, "|", "\\|", -1)
+}
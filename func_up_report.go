@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"html/template"
+	"os"
+	"path"
+	"time"
+)
+
+type htmlReport struct {
+	Time     string
+	Output   string
+	Podfiles []*OutputUpgradeGraph
+}
+
+// 输出-up的离线HTML报告，同一次运行的所有Podfile写入同一个报告
+func writeHTMLReport(dir string, date time.Time, podfiles []*OutputUpgradeGraph) {
+	tpl, e := template.New("report").Parse(_HTML_REPORT_TEMPLATE)
+	if e != nil {
+		printRed("报告模板错误: "+e.Error(), false)
+		return
+	}
+	report := &htmlReport{Time: date.Format("2006-01-02 15:04:05"), Output: dir, Podfiles: podfiles}
+	var buffer bytes.Buffer
+	if e := tpl.Execute(&buffer, report); e != nil {
+		printRed("生成报告错误: "+e.Error(), false)
+		return
+	}
+	p := path.Join(dir, "report.html")
+	if e := WriteFile(p, buffer.Bytes(), true, os.ModePerm); e != nil {
+		printRed("输出文件错误["+p+"]: "+e.Error(), false)
+		return
+	}
+	println("已输出报告: " + p)
+}
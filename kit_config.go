@@ -1,6 +1,8 @@
 package main
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"os"
 	"strings"
@@ -8,8 +10,11 @@ import (
 	"encoding/json"
 	"io/ioutil"
 
+	"os/exec"
 	"os/user"
 
+	"pandora/client"
+	"pandora/pod"
 	"path"
 
 	"strconv"
@@ -17,6 +22,12 @@ import (
 	"github.com/go-hayden-base/fs"
 )
 
+// .podspec求值方式: ipc每个spec执行一次pod ipc spec，ruby使用常驻的Ruby进程
+const (
+	__SPEC_EVALUATOR_IPC  = "ipc"
+	__SPEC_EVALUATOR_RUBY = "ruby"
+)
+
 const (
 	__ENV_RELEASE = iota
 	__ENV_ALPHA
@@ -32,11 +43,19 @@ type Config struct {
 	PodRepoRoot     string        `json:"pod_repo_root,omitempty" bson:"pod_repo_root,omitempty"`
 	PodRepos        []*ConfigRepo `json:"pod_repos,omitempty" bson:"pod_repos,omitempty"`
 	SpecThread      int           `json:"spec_thread,omitempty" bson:"spec_thread,omitempty"`
+	SpecEvaluator   string        `json:"spec_evaluator,omitempty" bson:"spec_evaluator,omitempty"`
+	SpecTimeout     int           `json:"spec_timeout,omitempty" bson:"spec_timeout,omitempty"`
+	SyncBatchSize   int           `json:"sync_batch_size,omitempty" bson:"sync_batch_size,omitempty"`
 }
 
 type ConfigRepo struct {
-	Name    string   `json:"name,omitempty" bson:"name,omitempty"`
-	Exclude []string `json:"exclude,omitempty" bson:"exclude,omitempty"`
+	Name     string   `json:"name,omitempty" bson:"name,omitempty"`
+	URL      string   `json:"url,omitempty" bson:"url,omitempty"`
+	Priority int      `json:"priority,omitempty" bson:"priority,omitempty"`
+	Exclude  []string `json:"exclude,omitempty" bson:"exclude,omitempty"`
+	Include  []string `json:"include,omitempty" bson:"include,omitempty"`
+
+	remoteURL string
 }
 
 func NewConfig() (*Config, error) {
@@ -88,11 +107,29 @@ func (s *Config) Check() error {
 	if len(s.PodRepos) == 0 {
 		return errors.New("请设置索引的Spec仓库！")
 	}
+	for _, repo := range s.PodRepos {
+		if _, e := newRepoFilter(repo); e != nil {
+			return errors.New("仓库 " + repo.Name + " 的exclude/include规则不正确! " + e.Error())
+		}
+	}
 	if s.SpecThread < 1 {
 		s.SpecThread = 5
 	} else if s.SpecThread > 20 {
 		s.SpecThread = 20
 	}
+	switch s.SpecEvaluator {
+	case "":
+		s.SpecEvaluator = __SPEC_EVALUATOR_IPC
+	case __SPEC_EVALUATOR_IPC, __SPEC_EVALUATOR_RUBY:
+	default:
+		return errors.New("不支持的spec_evaluator，仅支持 ipc 或 ruby！")
+	}
+	if s.SpecTimeout < 1 {
+		s.SpecTimeout = 60
+	}
+	if s.SyncBatchSize < 1 {
+		s.SyncBatchSize = 500
+	}
 	return nil
 }
 
@@ -111,3 +148,86 @@ func (s *Config) IsBeta() bool {
 func (s *Config) IsRelease() bool {
 	return s.Environment == __ENV_RELEASE
 }
+
+// 配置内容的摘要，记录在同步历史中用于区分不同配置下的同步
+func (s *Config) Hash() string {
+	b, e := json.Marshal(s)
+	if e != nil {
+		return ""
+	}
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// 仓库当前的git HEAD，非git仓库时返回空
+func (s *ConfigRepo) GitHead(podRepoRoot string) string {
+	b, e := exec.Command("git", "-C", path.Join(podRepoRoot, s.Name), "rev-parse", "HEAD").Output()
+	if e != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// 仓库的远端地址，未配置url时读取仓库目录下的.url文件或git remote
+func (s *ConfigRepo) RemoteURL(podRepoRoot string) string {
+	if s.URL != "" {
+		return s.URL
+	}
+	if s.remoteURL != "" {
+		return s.remoteURL
+	}
+	dir := path.Join(podRepoRoot, s.Name)
+	if b, e := ioutil.ReadFile(path.Join(dir, ".url")); e == nil {
+		s.remoteURL = strings.TrimSpace(string(b))
+	} else if b, e := exec.Command("git", "-C", dir, "config", "--get", "remote.origin.url").Output(); e == nil {
+		s.remoteURL = strings.TrimSpace(string(b))
+	}
+	return s.remoteURL
+}
+
+// 将Podfile中的source按顺序映射为已配置的仓库名，返回仓库名及未匹配的source
+func (s *Config) ReposForSources(sources []string) ([]string, []string) {
+	repos := make([]string, 0, len(sources))
+	unmatched := make([]string, 0, len(sources))
+	for _, source := range sources {
+		n := normalizeRepoURL(source)
+		found := false
+		for _, repo := range s.PodRepos {
+			if u := repo.RemoteURL(s.PodRepoRoot); u != "" && normalizeRepoURL(u) == n {
+				if !pod.ContainsString(repos, repo.Name) {
+					repos = append(repos, repo.Name)
+				}
+				found = true
+				break
+			}
+		}
+		if !found {
+			unmatched = append(unmatched, source)
+		}
+	}
+	return repos, unmatched
+}
+
+// 查询客户端使用的配置，仓库顺序与优先级同配置文件
+func (s *Config) ClientConfig() *client.Config {
+	conf := &client.Config{Repos: make([]*client.Repo, 0, len(s.PodRepos))}
+	for _, repo := range s.PodRepos {
+		conf.Repos = append(conf.Repos, &client.Repo{Name: repo.Name, Priority: repo.Priority})
+	}
+	return conf
+}
+
+// 统一仓库地址格式，例如 git@github.com:a/b.git 与 https://github.com/a/b 视为相同
+func normalizeRepoURL(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if idx := strings.Index(u, "://"); idx > -1 {
+		u = u[idx+3:]
+	} else if idx := strings.Index(u, ":"); idx > -1 {
+		u = u[:idx] + "/" + u[idx+1:]
+	}
+	if idx := strings.Index(u, "@"); idx > -1 && idx < strings.Index(u+"/", "/") {
+		u = u[idx+1:]
+	}
+	u = strings.TrimRight(u, "/")
+	return strings.TrimSuffix(u, ".git")
+}
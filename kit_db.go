@@ -1,31 +1,41 @@
 package main
 
 import (
-	"database/sql"
+	"pandora/client"
+	"pandora/pod"
+	"pandora/store"
 	"path"
-
-	_ "github.com/mattn/go-sqlite3"
+	"time"
 )
 
-func initDB() error {
-	dbpath := path.Join(_Conf.Workspace, "pandora.db")
-	var err error
-	_DB, err = sql.Open("sqlite3", dbpath)
-	if err != nil {
-		return err
+// memory为true时使用内存存储，不读写pandora.db
+func initDB(memory bool) error {
+	if memory {
+		_Client = client.New(_Conf.ClientConfig(), store.NewMemory())
+	} else {
+		dbpath := path.Join(_Conf.Workspace, "pandora.db")
+		var err error
+		_Client, err = client.Open(_Conf.ClientConfig(), dbpath)
+		if err != nil {
+			return err
+		}
+	}
+	_Client.OnSpecConflict = warnSpecConflict
+	pod.SetSpecCache(&specCache{store: _Client.Store()})
+	pod.SetSpecTimeout(time.Duration(_Conf.SpecTimeout) * time.Second)
+	if _Conf.SpecEvaluator == __SPEC_EVALUATOR_RUBY {
+		pod.SetSpecEvaluator(pod.NewRubyEvaluator(_Conf.SpecThread))
 	}
-	return createTablesIfNeed()
+	return nil
 }
 
-func createTablesIfNeed() error {
-	_, err := _DB.Exec(_SQL_REPO_TB_CREATE)
-	if err != nil {
-		return err
+// 内存存储模式下，执行命令前先索引本地Pod仓库
+func prepareMemoryStore() {
+	if !_Args.CheckSubargs("--memory") || _Args.Name == "-sync" {
+		return
 	}
-
-	_, err = _DB.Exec(_SQL_REPO_SYNCLOG_TB_CREATE)
-	if err != nil {
-		return err
+	println("使用内存存储，开始索引 ...")
+	if _, e := runSync(_Ctx, __SYNC_MODE_NORMAL); e != nil {
+		exitWithMessage(e.Error(), false)
 	}
-	return nil
 }
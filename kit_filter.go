@@ -0,0 +1,212 @@
+package main
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	ver "github.com/hashicorp/go-version"
+)
+
+// 未匹配include规则的模块及版本在统计中的规则名
+const __RULE_INCLUDE = "(include)"
+
+// 仓库的exclude/include规则，格式为 模块[@版本]
+// 模块: 精确名称(Foo)、通配符(Test*)，或包含 ^$+()|\{} 及 .* 的正则(.*Demo$)，正则需匹配整个模块名
+// 版本: 精确版本(Foo@1.2.3)，或以 <>=~! 开头的版本约束(Foo@<1.0)
+type repoRule struct {
+	raw        string
+	name       string
+	glob       string
+	reg        *regexp.Regexp
+	version    string
+	constraint ver.Constraints
+
+	// 本次索引中该规则排除的模块及版本数
+	modules  int64
+	versions int64
+}
+
+func newRepoRule(raw string) (*repoRule, error) {
+	s := strings.TrimSpace(raw)
+	r := &repoRule{raw: s}
+	if idx := strings.Index(s, "@"); idx > -1 {
+		s, r.version = s[:idx], strings.TrimSpace(s[idx+1:])
+		if r.version == "" {
+			return nil, errors.New(raw + ": @之后缺少版本")
+		}
+		if strings.ContainsAny(r.version[:1], "<>=~!") {
+			c, e := ver.NewConstraint(r.version)
+			if e != nil {
+				return nil, errors.New(raw + ": " + e.Error())
+			}
+			r.constraint, r.version = c, ""
+		}
+	}
+	if s == "" {
+		return nil, errors.New(raw + ": 缺少模块名")
+	}
+	switch {
+	case strings.ContainsAny(s, "^$+()|\\{}") || strings.Contains(s, ".*"):
+		reg, e := regexp.Compile("^(?:" + s + ")$")
+		if e != nil {
+			return nil, errors.New(raw + ": " + e.Error())
+		}
+		r.reg = reg
+	case strings.ContainsAny(s, "*?["):
+		if _, e := path.Match(s, ""); e != nil {
+			return nil, errors.New(raw + ": " + e.Error())
+		}
+		r.glob = s
+	default:
+		r.name = s
+	}
+	return r, nil
+}
+
+func (s *repoRule) isModuleRule() bool {
+	return s.version == "" && s.constraint == nil
+}
+
+func (s *repoRule) matchModule(module string) bool {
+	switch {
+	case s.reg != nil:
+		return s.reg.MatchString(module)
+	case s.glob != "":
+		ok, _ := path.Match(s.glob, module)
+		return ok
+	default:
+		return s.name == module
+	}
+}
+
+// 模块级规则匹配所有版本，无法解析的版本号不匹配版本约束
+func (s *repoRule) matchVersion(version string) bool {
+	if s.isModuleRule() {
+		return true
+	}
+	if s.constraint == nil {
+		return s.version == version
+	}
+	v, e := ver.NewVersion(version)
+	if e != nil {
+		return false
+	}
+	return s.constraint.Check(v)
+}
+
+// 一个仓库的规则，配置include时仅索引匹配include的模块及版本，exclude优先
+type repoFilter struct {
+	exclude []*repoRule
+	include []*repoRule
+
+	// 本次索引中未匹配include的模块及版本数
+	includeModules  int64
+	includeVersions int64
+}
+
+func newRepoFilter(repo *ConfigRepo) (*repoFilter, error) {
+	s := &repoFilter{exclude: make([]*repoRule, 0, len(repo.Exclude)), include: make([]*repoRule, 0, len(repo.Include))}
+	for _, raw := range repo.Exclude {
+		r, e := newRepoRule(raw)
+		if e != nil {
+			return nil, e
+		}
+		s.exclude = append(s.exclude, r)
+	}
+	for _, raw := range repo.Include {
+		r, e := newRepoRule(raw)
+		if e != nil {
+			return nil, e
+		}
+		s.include = append(s.include, r)
+	}
+	return s, nil
+}
+
+// 返回排除模块的规则，未匹配include时规则为nil；未配置规则的仓库filter为nil
+func (s *repoFilter) matchModule(module string) (*repoRule, bool) {
+	if s == nil {
+		return nil, false
+	}
+	for _, r := range s.exclude {
+		if r.isModuleRule() && r.matchModule(module) {
+			return r, true
+		}
+	}
+	if len(s.include) == 0 {
+		return nil, false
+	}
+	for _, r := range s.include {
+		if r.matchModule(module) {
+			return nil, false
+		}
+	}
+	return nil, true
+}
+
+// 返回排除版本的规则，未匹配include时规则为nil；模块级规则已由matchModule处理
+func (s *repoFilter) matchVersion(module string, version string) (*repoRule, bool) {
+	if s == nil {
+		return nil, false
+	}
+	for _, r := range s.exclude {
+		if !r.isModuleRule() && r.matchModule(module) && r.matchVersion(version) {
+			return r, true
+		}
+	}
+	if len(s.include) == 0 {
+		return nil, false
+	}
+	for _, r := range s.include {
+		if r.matchModule(module) && r.matchVersion(version) {
+			return nil, false
+		}
+	}
+	return nil, true
+}
+
+// 模块被排除时计数并返回true，可被多个协程调用
+func (s *repoFilter) skipModule(module string) bool {
+	r, ok := s.matchModule(module)
+	if !ok {
+		return false
+	}
+	if r != nil {
+		atomic.AddInt64(&r.modules, 1)
+	} else {
+		atomic.AddInt64(&s.includeModules, 1)
+	}
+	return true
+}
+
+// 版本被排除时计数并返回true，可被多个协程调用
+func (s *repoFilter) skipVersion(module string, version string) bool {
+	r, ok := s.matchVersion(module, version)
+	if !ok {
+		return false
+	}
+	if r != nil {
+		atomic.AddInt64(&r.versions, 1)
+	} else {
+		atomic.AddInt64(&s.includeVersions, 1)
+	}
+	return true
+}
+
+// 各规则排除的模块及版本数，未匹配include的计入"(include)"
+func (s *repoFilter) exclusions(repo string) []*OutputSyncExclusion {
+	if s == nil {
+		return nil
+	}
+	res := make([]*OutputSyncExclusion, 0, len(s.exclude)+1)
+	for _, r := range s.exclude {
+		res = append(res, &OutputSyncExclusion{Repo: repo, Rule: r.raw, Modules: int(atomic.LoadInt64(&r.modules)), Versions: int(atomic.LoadInt64(&r.versions))})
+	}
+	if len(s.include) > 0 {
+		res = append(res, &OutputSyncExclusion{Repo: repo, Rule: __RULE_INCLUDE, Modules: int(atomic.LoadInt64(&s.includeModules)), Versions: int(atomic.LoadInt64(&s.includeVersions))})
+	}
+	return res
+}
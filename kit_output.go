@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"pandora/client"
+	"pandora/pod"
+	"pandora/store"
+	"sort"
+	"time"
+
+	cp "github.com/fatih/color"
+)
+
+const (
+	__FORMAT_TEXT = "text"
+	__FORMAT_JSON = "json"
+)
+
+var _Format = __FORMAT_TEXT
+
+// 设置输出格式，json模式下日志输出到stderr，stdout仅输出结果
+func setupOutput(format string) bool {
+	switch format {
+	case "", __FORMAT_TEXT:
+		_Format = __FORMAT_TEXT
+	case __FORMAT_JSON:
+		_Format = __FORMAT_JSON
+		cp.Output = os.Stderr
+	default:
+		return false
+	}
+	return true
+}
+
+func isJSONOutput() bool {
+	return _Format == __FORMAT_JSON
+}
+
+func printJSON(v interface{}) {
+	b, e := json.MarshalIndent(v, "", "  ")
+	if e != nil {
+		printJSONError(e.Error())
+		return
+	}
+	os.Stdout.Write(b)
+	os.Stdout.WriteString("\n")
+}
+
+func printJSONError(msg string) {
+	b, _ := json.Marshal(&OutputError{Error: msg})
+	os.Stdout.Write(b)
+	os.Stdout.WriteString("\n")
+}
+
+// ** Output Schema **
+type OutputError struct {
+	Error string `json:"error"`
+}
+
+type OutputDepend struct {
+	Name        string `json:"name"`
+	Requirement string `json:"requirement"`
+}
+
+type OutputDepends struct {
+	Module       string          `json:"module"`
+	Version      string          `json:"version"`
+	Repos        []string        `json:"repos"`
+	Dependencies []*OutputDepend `json:"dependencies"`
+}
+
+type OutputSync struct {
+	SyncID    int64                `json:"sync_id"`
+	Resumed   bool                 `json:"resumed"`
+	Success   int                  `json:"success"`
+	Failure   int                  `json:"failure"`
+	Inserted  int                  `json:"inserted"`
+	Failed    int                  `json:"failed"`
+	Added     int                  `json:"added"`
+	Changed   int                  `json:"changed"`
+	Removed   int                  `json:"removed"`
+	CacheHit  int64                `json:"cache_hit"`
+	CacheMiss int64                `json:"cache_miss"`
+	Timeouts  []string             `json:"timeouts"`
+	Repos     []*OutputSyncRepo    `json:"repos"`
+	Failures  []*OutputSyncFailure `json:"failures"`
+	Errors    []string             `json:"errors"`
+
+	Exclusions []*OutputSyncExclusion `json:"exclusions"`
+}
+
+type OutputSyncExclusion struct {
+	Repo     string `json:"repo"`
+	Rule     string `json:"rule"`
+	Modules  int    `json:"modules"`
+	Versions int    `json:"versions"`
+}
+
+type OutputSyncRepo struct {
+	Name             string `json:"name"`
+	Success          int    `json:"success"`
+	Failure          int    `json:"failure"`
+	NoSpec           int    `json:"no_spec"`
+	MissingSpec      int    `json:"missing_spec"`
+	ParseFailure     int    `json:"parse_failure"`
+	EvaluatorFailure int    `json:"evaluator_failure"`
+	DuplicateKey     int    `json:"duplicate_key"`
+}
+
+type OutputSyncFailure struct {
+	Repo     string `json:"repo"`
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	Error    string `json:"error"`
+}
+
+type OutputFailures struct {
+	Total  int                   `json:"total"`
+	Groups []*OutputFailureGroup `json:"groups"`
+}
+
+type OutputFailureGroup struct {
+	Error string           `json:"error"`
+	Count int              `json:"count"`
+	Specs []*OutputFailure `json:"specs"`
+}
+
+type OutputFailure struct {
+	Repo     string `json:"repo"`
+	Module   string `json:"module"`
+	Version  string `json:"version"`
+	Path     string `json:"path"`
+	Category string `json:"category"`
+	CTime    string `json:"ctime"`
+}
+
+type OutputSyncLog struct {
+	ID         int64                  `json:"id"`
+	Time       string                 `json:"time"`
+	Status     string                 `json:"status"`
+	Duration   string                 `json:"duration"`
+	Added      int                    `json:"added"`
+	Changed    int                    `json:"changed"`
+	Removed    int                    `json:"removed"`
+	Failed     int                    `json:"failed"`
+	Version    string                 `json:"version"`
+	ConfigHash string                 `json:"config_hash"`
+	Repos      []*OutputSyncLogRepo   `json:"repos,omitempty"`
+	Changes    []*OutputSyncLogChange `json:"changes,omitempty"`
+}
+
+type OutputSyncLogRepo struct {
+	Name             string `json:"name"`
+	Success          int    `json:"success"`
+	MissingSpec      int    `json:"missing_spec"`
+	ParseFailure     int    `json:"parse_failure"`
+	EvaluatorFailure int    `json:"evaluator_failure"`
+	DuplicateKey     int    `json:"duplicate_key"`
+	GitHead          string `json:"git_head"`
+}
+
+type OutputSyncLogChange struct {
+	Action  string `json:"action"`
+	Repo    string `json:"repo"`
+	Module  string `json:"module"`
+	Version string `json:"version"`
+}
+
+type OutputNews struct {
+	Since   string              `json:"since"`
+	Total   int                 `json:"total"`
+	Modules []*OutputNewsModule `json:"modules"`
+}
+
+type OutputNewsModule struct {
+	Repo     string   `json:"repo"`
+	Module   string   `json:"module"`
+	Previous string   `json:"previous"`
+	Versions []string `json:"versions"`
+	Major    bool     `json:"major"`
+}
+
+type OutputUpgrade struct {
+	Output   string                `json:"output"`
+	Podfiles []*OutputUpgradeGraph `json:"podfiles"`
+}
+
+type OutputUpgradeGraph struct {
+	Path    string                          `json:"path"`
+	Repos   []string                        `json:"repos"`
+	Modules []*OutputGraphModule            `json:"modules"`
+	Targets map[string][]*OutputGraphModule `json:"targets,omitempty"`
+}
+
+type OutputGraphModule struct {
+	Name         string          `json:"name"`
+	Version      string          `json:"version"`
+	UpgradeTo    string          `json:"upgrade_to"`
+	UpgradeTag   string          `json:"upgrade_tag"`
+	UseVersion   string          `json:"use_version"`
+	Newest       string          `json:"newest"`
+	IsCommon     bool            `json:"is_common"`
+	IsImplicit   bool            `json:"is_implicit"`
+	IsLocal      bool            `json:"is_local"`
+	External     string          `json:"external,omitempty"`
+	Dependencies []*OutputDepend `json:"dependencies"`
+}
+
+func newOutputDepends(deps []*pod.DependBase) []*OutputDepend {
+	res := make([]*OutputDepend, 0, len(deps))
+	for _, aDep := range deps {
+		res = append(res, &OutputDepend{Name: aDep.N, Requirement: aDep.V})
+	}
+	return res
+}
+
+func newOutputDependsResult(res *client.DependsResult) *OutputDepends {
+	return &OutputDepends{Module: res.Module, Version: res.Version, Repos: res.Repos, Dependencies: newOutputDepends(res.Depends)}
+}
+
+func newOutputGraph(graphPodfile pod.GraphPodfile) []*OutputGraphModule {
+	res := make([]*OutputGraphModule, 0, len(graphPodfile))
+	for _, name := range graphPodfile.SortedNames() {
+		m := graphPodfile[name]
+		res = append(res, &OutputGraphModule{
+			Name:         m.Name,
+			Version:      m.Version,
+			UpgradeTo:    m.UpdateToVersion,
+			UpgradeTag:   m.UpgradeTag(),
+			UseVersion:   m.UseVersion(),
+			Newest:       m.NewestVersion,
+			IsCommon:     m.IsCommon,
+			IsImplicit:   m.IsNew,
+			IsLocal:      m.IsLocal,
+			External:     m.External(),
+			Dependencies: newOutputDepends(m.Depends),
+		})
+	}
+	return res
+}
+
+func newOutputTargets(targetGraphs map[string]pod.GraphPodfile) map[string][]*OutputGraphModule {
+	if len(targetGraphs) == 0 {
+		return nil
+	}
+	res := make(map[string][]*OutputGraphModule)
+	for name, aGP := range targetGraphs {
+		res[name] = newOutputGraph(aGP)
+	}
+	return res
+}
+
+func newOutputSyncLog(l *store.SyncLog) *OutputSyncLog {
+	return &OutputSyncLog{
+		ID:         l.ID,
+		Time:       l.Time.Local().Format("2006-01-02 15:04:05"),
+		Status:     l.Status,
+		Duration:   l.Duration.Round(time.Millisecond).String(),
+		Added:      l.Added,
+		Changed:    l.Changed,
+		Removed:    l.Removed,
+		Failed:     l.Failed,
+		Version:    l.Version,
+		ConfigHash: l.ConfigHash,
+	}
+}
+
+func newEmptyOutputSync() *OutputSync {
+	return &OutputSync{Repos: []*OutputSyncRepo{}, Failures: []*OutputSyncFailure{}, Timeouts: []string{}, Errors: []string{}, Exclusions: []*OutputSyncExclusion{}}
+}
+
+func newOutputSyncRepos(p *pod.Pod, result *pod.IndexResult) ([]*OutputSyncRepo, []*OutputSyncFailure) {
+	repos := make([]*OutputSyncRepo, 0, len(result.Repos))
+	for _, stats := range result.Repos {
+		repos = append(repos, &OutputSyncRepo{
+			Name:             stats.Name,
+			Success:          stats.Success,
+			Failure:          stats.Failure(""),
+			NoSpec:           stats.NoSpec,
+			MissingSpec:      stats.Failures[pod.INDEX_ERR_MISSING_SPEC],
+			ParseFailure:     stats.Failures[pod.INDEX_ERR_PARSE_FAILURE],
+			EvaluatorFailure: stats.Failures[pod.INDEX_ERR_EVALUATOR_FAILURE],
+			DuplicateKey:     stats.Failures[pod.INDEX_ERR_DUPLICATE_KEY],
+		})
+	}
+	failures := make([]*OutputSyncFailure, 0, 10)
+	for _, repo := range p.PodRepos {
+		for _, module := range repo.Modules {
+			for _, version := range module.Versions {
+				if version.Err == nil {
+					continue
+				}
+				failures = append(failures, &OutputSyncFailure{
+					Repo:     repo.Name,
+					Module:   module.Name,
+					Version:  version.Name,
+					Path:     version.Root + "/" + version.FileName,
+					Category: pod.SpecErrorCategory(version.Err),
+					Error:    version.Err.Error(),
+				})
+			}
+		}
+	}
+	sort.Slice(repos, func(i, j int) bool {
+		return repos[i].Name < repos[j].Name
+	})
+	return repos, failures
+}
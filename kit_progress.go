@@ -0,0 +1,78 @@
+package main
+
+import (
+	"pandora/pod"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const __PROGRESS_INTERVAL = time.Second
+
+// 同步进度，按仓库统计已处理的版本数并估算剩余时间，仅在单个协程中使用
+type syncProgress struct {
+	start     time.Time
+	lastPrint time.Time
+	total     int
+	done      int
+	repos     []*syncProgressRepo
+}
+
+type syncProgressRepo struct {
+	name  string
+	total int
+	done  int
+}
+
+func newSyncProgress(p *pod.Pod) *syncProgress {
+	now := time.Now()
+	s := &syncProgress{start: now, lastPrint: now, repos: make([]*syncProgressRepo, 0, len(p.PodRepos))}
+	for _, repo := range p.PodRepos {
+		n := repo.VersionCount()
+		s.total += n
+		s.repos = append(s.repos, &syncProgressRepo{name: repo.Name, total: n})
+	}
+	return s
+}
+
+// 处理完repo中的一个版本，距上次输出超过__PROGRESS_INTERVAL时输出进度
+func (s *syncProgress) add(repo string) {
+	s.done++
+	for _, r := range s.repos {
+		if r.name == repo {
+			r.done++
+			break
+		}
+	}
+	if time.Since(s.lastPrint) >= __PROGRESS_INTERVAL {
+		s.print()
+	}
+}
+
+func (s *syncProgress) print() {
+	s.lastPrint = time.Now()
+	percent := 100.0
+	if s.total > 0 {
+		percent = float64(s.done) * 100 / float64(s.total)
+	}
+	repos := make([]string, 0, len(s.repos))
+	for _, r := range s.repos {
+		repos = append(repos, r.name+" "+strconv.Itoa(r.done)+"/"+strconv.Itoa(r.total))
+	}
+	println("进度: " + strconv.Itoa(s.done) + "/" + strconv.Itoa(s.total) +
+		" (" + strconv.FormatFloat(percent, 'f', 1, 64) + "%) 预计剩余 " + s.eta() +
+		" | " + strings.Join(repos, ", "))
+}
+
+// 按已处理版本的平均耗时估算剩余时间
+func (s *syncProgress) eta() string {
+	if s.done == 0 {
+		return "未知"
+	}
+	if s.done >= s.total {
+		return "0s"
+	}
+	elapsed := time.Since(s.start)
+	remain := time.Duration(float64(elapsed) / float64(s.done) * float64(s.total-s.done))
+	return remain.Round(time.Second).String()
+}
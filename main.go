@@ -1,8 +1,12 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"os"
+	"os/signal"
+	"pandora/client"
+	"pandora/pod"
+	"syscall"
 
 	"time"
 
@@ -10,10 +14,17 @@ import (
 )
 
 var _Conf *Config
-var _DB *sql.DB
+var _Client *client.Client
 var _Args *Args
 
-func init() {
+// pandora版本，构建时可通过 -ldflags "-X main._Version=x.y.z" 指定
+var _Version = "dev"
+
+// 收到中断信号时取消，进行中的操作据此回滚后退出
+var _Ctx context.Context
+
+// 在main中而非init中初始化，测试时不依赖PANDORA_PATH及命令行参数
+func setup() {
 	// 获取配置
 	cfg, err := NewConfig()
 	if err != nil {
@@ -22,22 +33,35 @@ func init() {
 	}
 	_Conf = cfg
 
-	// 初始化数据库
-	err = initDB()
-	if err != nil {
-		printRed(err.Error(), true)
-		os.Exit(0)
-	}
-
 	// 初始化方法映射
 	_Args = NewArgs()
 	if _Args == nil {
 		printRed("无法解析参数！", true)
 		os.Exit(0)
 	}
+
+	// 先确定输出格式，json模式下数据库错误也以JSON输出
+	if !setupOutput(_Args.GetFirstSubArgs("--format")) {
+		printRed("不支持的输出格式，仅支持 json 或 text！", true)
+		os.Exit(1)
+	}
+
+	_Ctx = newSignalContext()
+
+	// 初始化数据库
+	err = initDB(_Args.CheckSubargs("--memory"))
+	if err != nil {
+		exitWithOutputError(err.Error(), true)
+	}
 	_Args.RegisterFunc("-sync", cmd_sync)
 	_Args.RegisterFunc("-dep", cmd_depend)
 	_Args.RegisterFunc("-up", cmd_upgrade)
+	_Args.RegisterFunc("-graph", cmd_graph)
+	_Args.RegisterFunc("-serve", cmd_serve)
+	_Args.RegisterFunc("-cache", cmd_cache)
+	_Args.RegisterFunc("-failures", cmd_failures)
+	_Args.RegisterFunc("-log", cmd_log)
+	_Args.RegisterFunc("-news", cmd_news)
 
 	if _Conf.IsDebug() {
 		_Args.RegisterFunc("-test_args", cmd_test_args)
@@ -46,6 +70,7 @@ func init() {
 }
 
 func main() {
+	setup()
 	l := len(os.Args)
 	if l < 2 {
 		println("请指定参数！")
@@ -53,7 +78,9 @@ func main() {
 		return
 	}
 	start := time.Now().UnixNano()
+	prepareMemoryStore()
 	ok := _Args.Exec()
+	pod.CloseSpecEvaluator()
 	if !ok {
 		printRed("未执行任何操作！", false)
 		printHelp()
@@ -62,3 +89,18 @@ func main() {
 	cost := float64(end-start) / float64(1000000000)
 	println("程序耗时:  " + strconv.FormatFloat(cost, 'f', -1, 64) + " 秒")
 }
+
+// 收到SIGINT/SIGTERM时取消返回的context，再次收到时立即退出
+func newSignalContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan os.Signal, 2)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-c
+		printRed("收到中断信号，正在取消 ...", false)
+		cancel()
+		<-c
+		os.Exit(130)
+	}()
+	return ctx
+}
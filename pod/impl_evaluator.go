@@ -0,0 +1,249 @@
+package pod
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 常驻求值进程使用的Ruby脚本
+// 请求: "<id>\t<path>\n"；响应: "<id> ok|err <字节数>\n" 后接对应字节数的JSON或错误信息
+// podspec中的puts等输出被重定向到stderr，避免破坏响应格式
+const __RUBY_EVALUATOR = `
+require 'cocoapods-core'
+out = $stdout.dup
+out.binmode
+out.sync = true
+$stdout = $stderr
+STDIN.each_line do |line|
+  id, path = line.chomp.split("\t", 2)
+  begin
+    body = Pod::Specification.from_file(path).to_json
+    status = 'ok'
+  rescue Exception => e
+    body = "#{e.class}: #{e.message}"
+    status = 'err'
+  end
+  body = body.b
+  out.write("#{id} #{status} #{body.bytesize}\n")
+  out.write(body)
+end
+`
+
+var (
+	ErrEvaluateTimeout = errors.New("spec求值超时")
+	errEvaluatorCrash  = errors.New("spec求值进程异常退出")
+)
+
+var _SpecEvaluator SpecEvaluator = new(ipcEvaluator)
+
+// 设置.podspec的求值方式，为nil时恢复为每个spec执行一次pod ipc spec
+func SetSpecEvaluator(e SpecEvaluator) {
+	if e == nil {
+		e = new(ipcEvaluator)
+	}
+	_SpecEvaluator = e
+}
+
+func CloseSpecEvaluator() error {
+	return _SpecEvaluator.Close()
+}
+
+var _SpecTimeout time.Duration
+
+// 单个spec求值的超时时间，小于等于0时不限制
+func SetSpecTimeout(d time.Duration) {
+	_SpecTimeout = d
+}
+
+// 按单个spec的超时时间求值，超时返回ErrEvaluateTimeout，ctx被取消时返回ctx.Err()
+func evaluateSpec(ctx context.Context, filePath string) ([]byte, error) {
+	evalCtx := ctx
+	if _SpecTimeout > 0 {
+		var cancel context.CancelFunc
+		evalCtx, cancel = context.WithTimeout(ctx, _SpecTimeout)
+		defer cancel()
+	}
+	b, err := _SpecEvaluator.Evaluate(evalCtx, filePath)
+	if err != nil {
+		if e := ctx.Err(); e != nil {
+			return nil, e
+		}
+		if evalCtx.Err() == context.DeadlineExceeded {
+			return nil, ErrEvaluateTimeout
+		}
+	}
+	return b, err
+}
+
+// ** ipcEvaluator Impl **
+// 执行失败时返回pod命令的错误输出，便于记录失败原因
+func (s *ipcEvaluator) Evaluate(ctx context.Context, filePath string) ([]byte, error) {
+	b, err := exec.CommandContext(ctx, "pod", "ipc", "spec", filePath).Output()
+	if e, ok := err.(*exec.ExitError); ok && ctx.Err() == nil {
+		if msg := strings.TrimSpace(string(e.Stderr)); msg != "" {
+			return nil, errors.New(msg)
+		}
+	}
+	return b, err
+}
+
+func (s *ipcEvaluator) Close() error {
+	return nil
+}
+
+// ** ProcessEvaluator Impl **
+
+// 使用Ruby脚本常驻求值，size为并发的进程数
+func NewRubyEvaluator(size int) *ProcessEvaluator {
+	return NewProcessEvaluator([]string{"ruby", "-e", __RUBY_EVALUATOR}, size)
+}
+
+// command为常驻进程的启动命令，进程需遵循__RUBY_EVALUATOR中的请求/响应格式；进程在首次使用时启动
+func NewProcessEvaluator(command []string, size int) *ProcessEvaluator {
+	if size < 1 {
+		size = 1
+	}
+	s := &ProcessEvaluator{workers: make(chan *evalProcess, size)}
+	for i := 0; i < size; i++ {
+		s.workers <- &evalProcess{command: command}
+	}
+	return s
+}
+
+func (s *ProcessEvaluator) Evaluate(ctx context.Context, filePath string) ([]byte, error) {
+	if strings.ContainsAny(filePath, "\t\n") {
+		return nil, errors.New("spec路径中不能包含制表符或换行符！")
+	}
+	var p *evalProcess
+	select {
+	case p = <-s.workers:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() {
+		s.workers <- p
+	}()
+	b, err := p.evaluate(ctx, filePath)
+	if err == errEvaluatorCrash {
+		// 进程崩溃时重启并重试一次，仍失败说明该spec导致进程退出
+		b, err = p.evaluate(ctx, filePath)
+	}
+	return b, err
+}
+
+// 结束全部进程，之后再次求值时会重新启动
+func (s *ProcessEvaluator) Close() error {
+	stopped := make([]*evalProcess, 0, cap(s.workers))
+	for i := 0; i < cap(s.workers); i++ {
+		p := <-s.workers
+		p.stop()
+		stopped = append(stopped, p)
+	}
+	for _, p := range stopped {
+		s.workers <- p
+	}
+	return nil
+}
+
+// ** evalProcess Impl **
+
+func (s *evalProcess) start() error {
+	cmd := exec.Command(s.command[0], s.command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err = cmd.Start(); err != nil {
+		return err
+	}
+	s.cmd = cmd
+	s.stdin = stdin
+	s.pipe = stdout
+	s.stdout = bufio.NewReader(stdout)
+	return nil
+}
+
+// 结束进程并关闭stdout，进行中的读取随即返回
+func (s *evalProcess) kill() {
+	s.stdin.Close()
+	s.cmd.Process.Kill()
+	s.pipe.Close()
+}
+
+// Wait会关闭stdout，须在没有进行中的读取时调用
+func (s *evalProcess) stop() {
+	if s.cmd == nil {
+		return
+	}
+	s.kill()
+	s.cmd.Wait()
+	s.cmd = nil
+}
+
+func (s *evalProcess) evaluate(ctx context.Context, filePath string) ([]byte, error) {
+	if s.cmd == nil {
+		if err := s.start(); err != nil {
+			return nil, err
+		}
+	}
+	s.seq++
+	id := strconv.Itoa(s.seq)
+	if _, err := io.WriteString(s.stdin, id+"\t"+filePath+"\n"); err != nil {
+		s.stop()
+		return nil, errEvaluatorCrash
+	}
+
+	c := make(chan *evalResponse, 1)
+	go func() {
+		b, err := s.readResponse(id)
+		c <- &evalResponse{b, err}
+	}()
+	select {
+	case res := <-c:
+		if res.err == errEvaluatorCrash {
+			s.stop()
+		}
+		return res.b, res.err
+	case <-ctx.Done():
+		// 无法单独取消一个请求，结束进程，待读取返回后再回收，下次求值时重新启动
+		s.kill()
+		<-c
+		s.stop()
+		return nil, ctx.Err()
+	}
+}
+
+func (s *evalProcess) readResponse(id string) ([]byte, error) {
+	header, err := s.stdout.ReadString('\n')
+	if err != nil {
+		return nil, errEvaluatorCrash
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 || fields[0] != id {
+		return nil, errEvaluatorCrash
+	}
+	l, err := strconv.Atoi(fields[2])
+	if err != nil || l < 0 {
+		return nil, errEvaluatorCrash
+	}
+	b := make([]byte, l)
+	if _, err = io.ReadFull(s.stdout, b); err != nil {
+		return nil, errEvaluatorCrash
+	}
+	if fields[1] != "ok" {
+		return nil, errors.New(string(b))
+	}
+	return b, nil
+}
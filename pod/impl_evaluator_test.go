@@ -0,0 +1,190 @@
+package pod
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 以测试程序自身作为常驻求值进程，按spec路径的文件名模拟不同的行为
+// crash不响应直接退出；crash-once首次求值时退出，重启后正常响应；slow长时间不响应；bad返回求值错误
+// 其他返回包含换行及多字节字符的JSON
+const __EVALUATOR_HELPER_ENV = "PANDORA_TEST_EVALUATOR_HELPER"
+
+func TestEvaluatorHelperProcess(t *testing.T) {
+	if os.Getenv(__EVALUATOR_HELPER_ENV) != "1" {
+		return
+	}
+	out := bufio.NewWriter(os.Stdout)
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		items := strings.SplitN(scanner.Text(), "\t", 2)
+		id, p := items[0], items[1]
+		status, body := "ok", fmt.Sprintf("{\n  \"name\": \"模块\",\n  \"path\": %q\n}", p)
+		switch filepath.Base(p) {
+		case "crash":
+			os.Exit(1)
+		case "crash-once":
+			if _, e := os.Stat(p + ".crashed"); e != nil {
+				os.WriteFile(p+".crashed", nil, 0644)
+				os.Exit(1)
+			}
+		case "slow":
+			time.Sleep(time.Minute)
+		case "bad":
+			status, body = "err", "RuntimeError: bad spec"
+		}
+		fmt.Fprintf(out, "%s %s %d\n%s", id, status, len(body), body)
+		out.Flush()
+	}
+	os.Exit(0)
+}
+
+func newTestEvaluator(t *testing.T, size int) *ProcessEvaluator {
+	t.Setenv(__EVALUATOR_HELPER_ENV, "1")
+	s := NewProcessEvaluator([]string{os.Args[0], "-test.run=^TestEvaluatorHelperProcess$"}, size)
+	t.Cleanup(func() {
+		s.Close()
+	})
+	return s
+}
+
+func checkSpec(s SpecEvaluator, p string) error {
+	b, e := s.Evaluate(context.Background(), p)
+	if e != nil {
+		return fmt.Errorf("%s: %v", p, e)
+	}
+	if expect := fmt.Sprintf("{\n  \"name\": \"模块\",\n  \"path\": %q\n}", p); string(b) != expect {
+		return fmt.Errorf("%s: 响应为 %q", p, b)
+	}
+	return nil
+}
+
+func expectSpec(t *testing.T, s SpecEvaluator, p string) {
+	t.Helper()
+	if e := checkSpec(s, p); e != nil {
+		t.Fatal(e)
+	}
+}
+
+// 进程ID，未启动时为0
+func workerPids(s *ProcessEvaluator) []int {
+	res := make([]int, 0, cap(s.workers))
+	for i := 0; i < cap(s.workers); i++ {
+		p := <-s.workers
+		pid := 0
+		if p.cmd != nil {
+			pid = p.cmd.Process.Pid
+		}
+		res = append(res, pid)
+		s.workers <- p
+	}
+	return res
+}
+
+func TestProcessEvaluatorFraming(t *testing.T) {
+	s := newTestEvaluator(t, 1)
+	dir := t.TempDir()
+	for _, name := range []string{"A.podspec", "B.podspec", "带空格 的.podspec"} {
+		expectSpec(t, s, filepath.Join(dir, name))
+	}
+	pid := workerPids(s)[0]
+
+	_, e := s.Evaluate(context.Background(), filepath.Join(dir, "bad"))
+	if e == nil || e.Error() != "RuntimeError: bad spec" {
+		t.Fatalf("求值错误: %v", e)
+	}
+	if _, e = s.Evaluate(context.Background(), filepath.Join(dir, "a\tb")); e == nil {
+		t.Fatal("路径包含制表符时应返回错误")
+	}
+	// 求值错误不影响进程，后续请求复用同一进程
+	expectSpec(t, s, filepath.Join(dir, "C.podspec"))
+	if p := workerPids(s)[0]; p != pid {
+		t.Fatalf("进程被重启: %d -> %d", pid, p)
+	}
+}
+
+func TestProcessEvaluatorCrash(t *testing.T) {
+	s := newTestEvaluator(t, 1)
+	dir := t.TempDir()
+
+	// 崩溃后重启并重试一次
+	expectSpec(t, s, filepath.Join(dir, "crash-once"))
+
+	// 重试仍崩溃时返回错误，下次求值重新启动进程
+	if _, e := s.Evaluate(context.Background(), filepath.Join(dir, "crash")); e != errEvaluatorCrash {
+		t.Fatalf("进程崩溃: %v", e)
+	}
+	if pid := workerPids(s)[0]; pid != 0 {
+		t.Fatalf("崩溃的进程未回收: %d", pid)
+	}
+	expectSpec(t, s, filepath.Join(dir, "A.podspec"))
+}
+
+func TestProcessEvaluatorTimeout(t *testing.T) {
+	s := newTestEvaluator(t, 2)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, e := s.Evaluate(ctx, filepath.Join(dir, "slow")); e != context.DeadlineExceeded {
+		t.Fatalf("超时: %v", e)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("超时后未及时返回: %s", d)
+	}
+	expectSpec(t, s, filepath.Join(dir, "A.podspec"))
+
+	// evaluateSpec按单个spec的超时时间返回ErrEvaluateTimeout
+	SetSpecEvaluator(s)
+	SetSpecTimeout(200 * time.Millisecond)
+	defer func() {
+		SetSpecEvaluator(nil)
+		SetSpecTimeout(0)
+	}()
+	if _, e := evaluateSpec(context.Background(), filepath.Join(dir, "slow")); e != ErrEvaluateTimeout {
+		t.Fatalf("spec超时: %v", e)
+	}
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, e := evaluateSpec(ctx, filepath.Join(dir, "A.podspec")); e != context.Canceled {
+		t.Fatalf("ctx取消: %v", e)
+	}
+}
+
+func TestProcessEvaluatorClose(t *testing.T) {
+	s := newTestEvaluator(t, 3)
+	dir := t.TempDir()
+	if e := s.Close(); e != nil {
+		t.Fatal(e)
+	}
+
+	c := make(chan error)
+	for i := 0; i < 6; i++ {
+		go func(i int) {
+			c <- checkSpec(s, filepath.Join(dir, fmt.Sprintf("M%d.podspec", i)))
+		}(i)
+	}
+	for i := 0; i < 6; i++ {
+		if e := <-c; e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if e := s.Close(); e != nil {
+		t.Fatal(e)
+	}
+	for _, pid := range workerPids(s) {
+		if pid != 0 {
+			t.Fatalf("Close后进程未结束: %d", pid)
+		}
+	}
+	// Close后再次求值时重新启动
+	expectSpec(t, s, filepath.Join(dir, "A.podspec"))
+}
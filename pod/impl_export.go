@@ -0,0 +1,241 @@
+package pod
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ** GraphPodfile Export **
+
+// 从roots出发导出依赖图，roots为空时以全部非隐性依赖为起点；MaxDepth大于0时仅保留该深度以内的节点
+func (s GraphPodfile) Export(roots []string, opt *GraphExportOption) *GraphExport {
+	if opt == nil {
+		opt = new(GraphExportOption)
+	}
+	if len(roots) == 0 {
+		for _, name := range s.SortedNames() {
+			if !s[name].IsNew {
+				roots = append(roots, name)
+			}
+		}
+	}
+	depth := make(map[string]int)
+	queue := make([]string, 0, len(s))
+	for _, name := range roots {
+		if _, ok := s[name]; ok {
+			if _, ok := depth[name]; !ok {
+				depth[name] = 0
+				queue = append(queue, name)
+			}
+		}
+	}
+	edges := make([]*GraphEdge, 0, len(s))
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		d := depth[name]
+		if opt.MaxDepth > 0 && d >= opt.MaxDepth {
+			continue
+		}
+		for _, dep := range s[name].Depends {
+			to, ok := s.resolveName(dep.Name())
+			if !ok || to == name {
+				continue
+			}
+			edges = append(edges, &GraphEdge{From: name, To: to, Label: dep.Version()})
+			if _, ok := depth[to]; !ok {
+				depth[to] = d + 1
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	res := new(GraphExport)
+	for name, d := range depth {
+		m := s[name]
+		res.Nodes = append(res.Nodes, &GraphNode{
+			Name:       m.Name,
+			Version:    m.Version,
+			UpgradeTo:  m.UseVersion(),
+			UpgradeTag: m.UpgradeTag(),
+			IsCommon:   m.IsCommon,
+			IsNew:      m.IsNew,
+			IsLocal:    m.IsLocal,
+			Depth:      d,
+		})
+	}
+	res.Edges = edges
+	if opt.CollapseSubspecs {
+		res.collapseSubspecs()
+	}
+	res.sort()
+	return res
+}
+
+// 依赖名在图中对应的模块，找不到子模块时向上查找父模块
+func (s GraphPodfile) resolveName(name string) (string, bool) {
+	for {
+		if _, ok := s[name]; ok {
+			return name, true
+		}
+		var ok bool
+		name, ok = ModuleBase(name)
+		if !ok {
+			return "", false
+		}
+	}
+}
+
+// ** GraphExport Impl **
+
+// 将子模块合并到其基础模块
+func (s *GraphExport) collapseSubspecs() {
+	nodes := make(map[string]*GraphNode)
+	for _, n := range s.Nodes {
+		base := BaseModule(n.Name)
+		old, ok := nodes[base]
+		if !ok {
+			c := *n
+			c.Name = base
+			nodes[base] = &c
+			continue
+		}
+		old.IsCommon = old.IsCommon || n.IsCommon
+		old.IsNew = old.IsNew && n.IsNew
+		old.IsLocal = old.IsLocal || n.IsLocal
+		if n.Depth < old.Depth {
+			old.Depth = n.Depth
+		}
+		if old.Version == "" {
+			old.Version, old.UpgradeTo, old.UpgradeTag = n.Version, n.UpgradeTo, n.UpgradeTag
+		}
+	}
+	edges := make(map[string]*GraphEdge)
+	for _, e := range s.Edges {
+		from, to := BaseModule(e.From), BaseModule(e.To)
+		if from == to {
+			continue
+		}
+		key := from + "\x00" + to
+		old, ok := edges[key]
+		if !ok {
+			edges[key] = &GraphEdge{From: from, To: to, Label: e.Label}
+		} else if e.Label != "" && !ContainsString(strings.Split(old.Label, " | "), e.Label) {
+			if old.Label == "" {
+				old.Label = e.Label
+			} else {
+				old.Label += " | " + e.Label
+			}
+		}
+	}
+	s.Nodes = make([]*GraphNode, 0, len(nodes))
+	for _, n := range nodes {
+		s.Nodes = append(s.Nodes, n)
+	}
+	s.Edges = make([]*GraphEdge, 0, len(edges))
+	for _, e := range edges {
+		s.Edges = append(s.Edges, e)
+	}
+}
+
+func (s *GraphExport) sort() {
+	sort.Slice(s.Nodes, func(i, j int) bool {
+		return s.Nodes[i].Name < s.Nodes[j].Name
+	})
+	sort.Slice(s.Edges, func(i, j int) bool {
+		if s.Edges[i].From != s.Edges[j].From {
+			return s.Edges[i].From < s.Edges[j].From
+		}
+		return s.Edges[i].To < s.Edges[j].To
+	})
+}
+
+// 按基础模块分组，仅包含一个节点且无子模块的分组也会返回
+func (s *GraphExport) groups() ([]string, map[string][]*GraphNode) {
+	groups := make(map[string][]*GraphNode)
+	names := make([]string, 0, len(s.Nodes))
+	for _, n := range s.Nodes {
+		base := BaseModule(n.Name)
+		if _, ok := groups[base]; !ok {
+			names = append(names, base)
+		}
+		groups[base] = append(groups[base], n)
+	}
+	sort.Strings(names)
+	return names, groups
+}
+
+// 节点显示的版本，有升级时显示 旧版本 -> 新版本
+func (s *GraphNode) VersionLabel() string {
+	if s.UpgradeTag != "-" && s.UpgradeTo != "" && s.UpgradeTo != s.Version {
+		return s.Version + " -> " + s.UpgradeTo
+	}
+	if s.UpgradeTo != "" {
+		return s.UpgradeTo
+	}
+	return s.Version
+}
+
+// ** DOT **
+func (s *GraphExport) DOT(name string) []byte {
+	var buffer bytes.Buffer
+	buffer.WriteString("digraph " + dotQuote(name) + " {\n")
+	buffer.WriteString("  rankdir=LR;\n")
+	buffer.WriteString("  node [shape=box, style=\"rounded,filled\", fillcolor=white, fontname=Helvetica];\n")
+	buffer.WriteString("  edge [fontname=Helvetica, fontsize=10];\n")
+	names, groups := s.groups()
+	for idx, base := range names {
+		nodes := groups[base]
+		clustered := len(nodes) > 1 || nodes[0].Name != base
+		indent := "  "
+		if clustered {
+			buffer.WriteString("  subgraph " + dotQuote("cluster_"+strconv.Itoa(idx)) + " {\n")
+			buffer.WriteString("    label=" + dotQuote(base) + "; style=dashed;\n")
+			indent = "    "
+		}
+		for _, n := range nodes {
+			buffer.WriteString(indent + dotQuote(n.Name) + " [" + n.dotAttrs() + "];\n")
+		}
+		if clustered {
+			buffer.WriteString("  }\n")
+		}
+	}
+	for _, e := range s.Edges {
+		buffer.WriteString("  " + dotQuote(e.From) + " -> " + dotQuote(e.To))
+		if e.Label != "" {
+			buffer.WriteString(" [label=" + dotQuote(e.Label) + "]")
+		}
+		buffer.WriteString(";\n")
+	}
+	buffer.WriteString("}\n")
+	return buffer.Bytes()
+}
+
+func (s *GraphNode) dotAttrs() string {
+	label := s.Name
+	if v := s.VersionLabel(); v != "" {
+		label += "\\n" + v
+	}
+	attrs := []string{"label=" + dotQuote(label)}
+	switch {
+	case s.IsLocal:
+		attrs = append(attrs, "fillcolor=lightgrey")
+	case s.IsNew:
+		attrs = append(attrs, "fillcolor=lightyellow", "style=\"rounded,filled,dashed\"")
+	case s.IsCommon:
+		attrs = append(attrs, "fillcolor=lightblue")
+	}
+	switch s.UpgradeTag {
+	case "up":
+		attrs = append(attrs, "color=forestgreen", "penwidth=2")
+	case "down":
+		attrs = append(attrs, "color=red", "penwidth=2")
+	}
+	return strings.Join(attrs, ", ")
+}
+
+func dotQuote(s string) string {
+	return "\"" + strings.Replace(s, "\"", "\\\"", -1) + "\""
+}
@@ -0,0 +1,187 @@
+package pod
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Mermaid和PlantUML在节点过多时难以渲染，未指定MaxNodes时使用该上限
+const GRAPH_DOC_MAX_NODES = 150
+
+// 按深度保留至多max个节点，返回裁剪后的图以及被省略的节点数和边数
+func (s *GraphExport) Limit(max int) (*GraphExport, int, int) {
+	if max <= 0 || len(s.Nodes) <= max {
+		return s, 0, 0
+	}
+	nodes := make([]*GraphNode, len(s.Nodes))
+	copy(nodes, s.Nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Depth < nodes[j].Depth
+	})
+	kept := make(map[string]bool)
+	res := new(GraphExport)
+	for _, n := range nodes[:max] {
+		kept[n.Name] = true
+	}
+	for _, n := range s.Nodes {
+		if kept[n.Name] {
+			res.Nodes = append(res.Nodes, n)
+		}
+	}
+	for _, e := range s.Edges {
+		if kept[e.From] && kept[e.To] {
+			res.Edges = append(res.Edges, e)
+		}
+	}
+	return res, len(s.Nodes) - len(res.Nodes), len(s.Edges) - len(res.Edges)
+}
+
+// 节点的样式类别
+func (s *GraphNode) styleClasses() []string {
+	res := make([]string, 0, 2)
+	switch {
+	case s.IsLocal:
+		res = append(res, "local")
+	case s.IsNew:
+		res = append(res, "implicit")
+	case s.IsCommon:
+		res = append(res, "common")
+	}
+	if s.UpgradeTag == "up" || s.UpgradeTag == "down" {
+		res = append(res, s.UpgradeTag)
+	}
+	return res
+}
+
+func limitSummary(nodes int, edges int) string {
+	return "图过大，已省略 " + strconv.Itoa(nodes) + " 个模块及 " + strconv.Itoa(edges) + " 条依赖"
+}
+
+// ** Mermaid **
+func (s *GraphExport) Mermaid(name string, maxNodes int) []byte {
+	if maxNodes <= 0 {
+		maxNodes = GRAPH_DOC_MAX_NODES
+	}
+	g, omitNodes, omitEdges := s.Limit(maxNodes)
+	ids := make(map[string]string)
+	for idx, n := range g.Nodes {
+		ids[n.Name] = "n" + strconv.Itoa(idx)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("%% " + name + "\n")
+	buffer.WriteString("flowchart LR\n")
+	names, groups := g.groups()
+	for idx, base := range names {
+		nodes := groups[base]
+		clustered := len(nodes) > 1 || nodes[0].Name != base
+		indent := "  "
+		if clustered {
+			buffer.WriteString("  subgraph g" + strconv.Itoa(idx) + " [" + mermaidQuote(base) + "]\n")
+			indent = "    "
+		}
+		for _, n := range nodes {
+			label := n.Name
+			if v := n.VersionLabel(); v != "" {
+				label += "<br/>" + v
+			}
+			buffer.WriteString(indent + ids[n.Name] + "[" + mermaidQuote(label) + "]\n")
+		}
+		if clustered {
+			buffer.WriteString("  end\n")
+		}
+	}
+	for _, e := range g.Edges {
+		buffer.WriteString("  " + ids[e.From] + " -->")
+		if e.Label != "" {
+			buffer.WriteString("|" + mermaidQuote(e.Label) + "|")
+		}
+		buffer.WriteString(" " + ids[e.To] + "\n")
+	}
+	if omitNodes > 0 {
+		buffer.WriteString("  more[" + mermaidQuote(limitSummary(omitNodes, omitEdges)) + "]:::summary\n")
+	}
+	buffer.WriteString("  classDef local fill:#eeeeee,stroke:#999999\n")
+	buffer.WriteString("  classDef implicit fill:#fff9c4,stroke-dasharray:5 5\n")
+	buffer.WriteString("  classDef common fill:#dbeafe\n")
+	buffer.WriteString("  classDef up stroke:#2e7d32,stroke-width:2px\n")
+	buffer.WriteString("  classDef down stroke:#c62828,stroke-width:2px\n")
+	buffer.WriteString("  classDef summary fill:#ffffff,stroke:#c62828,color:#c62828\n")
+	for _, n := range g.Nodes {
+		for _, c := range n.styleClasses() {
+			buffer.WriteString("  class " + ids[n.Name] + " " + c + "\n")
+		}
+	}
+	return buffer.Bytes()
+}
+
+func mermaidQuote(s string) string {
+	return "\"" + strings.Replace(s, "\"", "#quot;", -1) + "\""
+}
+
+// ** PlantUML **
+func (s *GraphExport) PlantUML(name string, maxNodes int) []byte {
+	if maxNodes <= 0 {
+		maxNodes = GRAPH_DOC_MAX_NODES
+	}
+	g, omitNodes, omitEdges := s.Limit(maxNodes)
+	ids := make(map[string]string)
+	for idx, n := range g.Nodes {
+		ids[n.Name] = "n" + strconv.Itoa(idx)
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("@startuml\n")
+	buffer.WriteString("title " + name + "\n")
+	buffer.WriteString("left to right direction\n")
+	buffer.WriteString("skinparam componentStyle rectangle\n")
+	buffer.WriteString("skinparam component {\n")
+	buffer.WriteString("  BackgroundColor<<local>> #EEEEEE\n")
+	buffer.WriteString("  BackgroundColor<<implicit>> #FFF9C4\n")
+	buffer.WriteString("  BackgroundColor<<common>> #DBEAFE\n")
+	buffer.WriteString("  BorderColor<<up>> #2E7D32\n")
+	buffer.WriteString("  BorderColor<<down>> #C62828\n")
+	buffer.WriteString("}\n")
+	names, groups := g.groups()
+	for _, base := range names {
+		nodes := groups[base]
+		clustered := len(nodes) > 1 || nodes[0].Name != base
+		indent := ""
+		if clustered {
+			buffer.WriteString("package " + plantumlQuote(base) + " {\n")
+			indent = "  "
+		}
+		for _, n := range nodes {
+			label := n.Name
+			if v := n.VersionLabel(); v != "" {
+				label += "\\n" + v
+			}
+			buffer.WriteString(indent + "component " + plantumlQuote(label) + " as " + ids[n.Name])
+			for _, c := range n.styleClasses() {
+				buffer.WriteString(" <<" + c + ">>")
+			}
+			buffer.WriteString("\n")
+		}
+		if clustered {
+			buffer.WriteString("}\n")
+		}
+	}
+	for _, e := range g.Edges {
+		buffer.WriteString(ids[e.From] + " --> " + ids[e.To])
+		if e.Label != "" {
+			buffer.WriteString(" : " + e.Label)
+		}
+		buffer.WriteString("\n")
+	}
+	if omitNodes > 0 {
+		buffer.WriteString("note as summary\n  " + limitSummary(omitNodes, omitEdges) + "\nend note\n")
+	}
+	buffer.WriteString("@enduml\n")
+	return buffer.Bytes()
+}
+
+func plantumlQuote(s string) string {
+	return "\"" + strings.Replace(s, "\"", "'", -1) + "\""
+}
@@ -2,6 +2,7 @@ package pod
 
 import (
 	"bytes"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -22,6 +23,18 @@ func (s *GraphModule) UpgradeTag() string {
 	}
 }
 
+// 是否为:git等非本地的外部来源，此类模块不从索引升级
+func (s *GraphModule) IsExternal() bool {
+	return s.Source != nil && !s.IsLocal
+}
+
+func (s *GraphModule) External() string {
+	if s.Source == nil {
+		return ""
+	}
+	return s.Source.String()
+}
+
 func (s *GraphModule) UseVersion() string {
 	if len(s.UpdateToVersion) == 0 {
 		return s.Version
@@ -67,6 +80,35 @@ func (s GraphPodfile) Check() []*DependBase {
 	return unfound
 }
 
+// 比较两个依赖图，返回仅存在于一方或使用版本不同的模块，按模块名排序
+func (s GraphPodfile) Diff(other GraphPodfile) []*GraphModuleDiff {
+	res := make([]*GraphModuleDiff, 0, 10)
+	for name, a := range s {
+		b, ok := other[name]
+		if !ok || a.UseVersion() != b.UseVersion() {
+			res = append(res, &GraphModuleDiff{Name: name, A: a, B: b})
+		}
+	}
+	for name, b := range other {
+		if _, ok := s[name]; !ok {
+			res = append(res, &GraphModuleDiff{Name: name, B: b})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+func (s GraphPodfile) SortedNames() []string {
+	res := make([]string, 0, len(s))
+	for name := range s {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
+
 func ModuleBase(s string) (string, bool) {
 	tmp := strings.Split(s, "/")
 	if len(tmp) < 2 {
@@ -80,6 +122,7 @@ func (s GraphPodfile) Bytes() []byte {
 	for _, m := range s {
 		buffer.WriteString(m.Name + "," + strconv.FormatBool(m.IsCommon) + "," + strconv.FormatBool(m.IsNew) + "," + strconv.FormatBool(m.IsLocal) + ",")
 		buffer.WriteString(m.Version + "," + m.UpdateToVersion + "," + m.UpgradeTag() + "," + m.NewestVersion + ",")
+		buffer.WriteString(strings.Replace(m.External(), ",", " ", -1) + ",")
 		for _, aDep := range m.Depends {
 			buffer.WriteString(aDep.String() + " ")
 		}
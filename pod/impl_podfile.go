@@ -1,10 +1,14 @@
 package pod
 
 import (
+	"context"
 	"os/exec"
+	"path/filepath"
+	"strings"
 
 	"path"
 
+	"github.com/go-hayden-base/fs"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -59,6 +63,61 @@ func (s *Podfile) TargetWithName(name string) *Target {
 	return nil
 }
 
+// 返回全部非abstract的target，没有时返回根target
+func (s *Podfile) ConcreteTargets() []*Target {
+	res := make([]*Target, 0, len(s.Targets))
+	for _, target := range s.Targets {
+		if !target.Abstract {
+			res = append(res, target)
+		}
+	}
+	if len(res) == 0 && s.Root != nil {
+		res = append(res, s.Root)
+	}
+	return res
+}
+
+// 返回target的有效依赖
+func (s *Podfile) TargetDepends(name string) []*Depend {
+	target := s.TargetWithName(name)
+	if target == nil {
+		return nil
+	}
+	return target.EffectiveDepends()
+}
+
+// 是否继承父target的依赖，inherit! :search_paths与inherit! :none仅使用自身的依赖
+func (s *Target) InheritsDepends() bool {
+	return s.Inheritance == "" || s.Inheritance == TARGET_INHERITANCE_COMPLETE
+}
+
+// 返回target的有效依赖，包含沿父target链继承的依赖，同名依赖以子target为准
+func (s *Target) EffectiveDepends() []*Depend {
+	res := make([]*Depend, 0, len(s.Depends))
+	added := make(map[string]bool)
+	for target := s; target != nil; target = target.Parent {
+		for _, dep := range target.Depends {
+			if _, ok := added[dep.Name()]; ok {
+				continue
+			}
+			added[dep.Name()] = true
+			res = append(res, dep)
+		}
+		if !target.InheritsDepends() {
+			break
+		}
+	}
+	return res
+}
+
+// 返回target的完整路径，例如 Pods/App/AppTests
+func (s *Target) Path() string {
+	if s.Parent == nil {
+		return s.Name
+	}
+	return s.Parent.Path() + "/" + s.Name
+}
+
 func (s *Target) DepndWithName(name string) *Depend {
 	for _, dep := range s.Depends {
 		if dep.Name() == name {
@@ -90,9 +149,16 @@ func (s *Target) HasDepend(name string) bool {
 
 func (s *Podfile) Print() {
 	for _, target := range s.Targets {
-		println("-> " + target.Name)
+		tag := ""
+		if target.Abstract {
+			tag = " (abstract)"
+		}
+		if target.Inheritance != "" {
+			tag += " (inherit: " + target.Inheritance + ")"
+		}
+		println("-> " + target.Path() + tag)
 		for _, depend := range target.Depends {
-			println("   -", depend.Name, depend.Version, depend.Type, depend.SpecPath)
+			println("   -", depend.Name(), depend.Version(), depend.Type, depend.SpecPath)
 		}
 	}
 }
@@ -105,8 +171,57 @@ func (s *Depend) IsLocal() bool {
 	return s.SpecPath != ""
 }
 
-func NewPodfile(filePath string, rel bool) (*Podfile, error) {
-	b, e := exec.Command("pod", "ipc", "podfile", filePath).Output()
+func (s *Depend) ExternalSource() *DependSource {
+	return s.Source
+}
+
+// ** DependSource Impl **
+func (s *DependSource) IsGit() bool {
+	return s.Git != ""
+}
+
+func (s *DependSource) String() string {
+	if s.Git != "" {
+		res := "git: " + s.Git
+		if s.Branch != "" {
+			res += " branch: " + s.Branch
+		}
+		if s.Tag != "" {
+			res += " tag: " + s.Tag
+		}
+		if s.Commit != "" {
+			res += " commit: " + s.Commit
+		}
+		return res
+	}
+	if s.Path != "" {
+		return "path: " + s.Path
+	}
+	if s.Podspec != "" {
+		return "podspec: " + s.Podspec
+	}
+	return ""
+}
+
+// 生成Podfile中pod行的选项，例如 :git => 'url', :tag => '1.0.0'
+func (s *DependSource) PodfileOptions() string {
+	opts := make([]string, 0, 3)
+	add := func(k, v string) {
+		if v != "" {
+			opts = append(opts, ":"+k+" => '"+v+"'")
+		}
+	}
+	add("git", s.Git)
+	add("branch", s.Branch)
+	add("tag", s.Tag)
+	add("commit", s.Commit)
+	add("path", s.Path)
+	add("podspec", s.Podspec)
+	return strings.Join(opts, ", ")
+}
+
+func NewPodfile(ctx context.Context, filePath string, rel bool) (*Podfile, error) {
+	b, e := exec.CommandContext(ctx, "pod", "ipc", "podfile", filePath).Output()
 	if e != nil {
 		return nil, e
 	}
@@ -122,27 +237,36 @@ func NewPodfile(filePath string, rel bool) (*Podfile, error) {
 	podfile := new(Podfile)
 	podfile.Targets = make([]*Target, 0, 10)
 	for _, a := range pf.Target_definitions {
-		// 读取子Targert
-		for _, b := range a.Children {
-			target := new(Target)
-			target.Name = b.Name
-			target.Depends = generateDepend(dir, b.Dependencies)
-			podfile.Targets = append(podfile.Targets, target)
-		}
-
-		// 读取主Target
-		if len(a.Dependencies) > 0 {
-			target := new(Target)
-			target.Name = "*"
-			target.Depends = generateDepend(dir, a.Dependencies)
-			podfile.Targets = append(podfile.Targets, target)
+		root := podfile.addTarget(dir, a, nil)
+		if podfile.Root == nil {
+			podfile.Root = root
 		}
 	}
 	podfile.FilePath = filePath
+	podfile.Sources = pf.Sources
+	if e = podfile.readSections(); e != nil {
+		return nil, e
+	}
 	return podfile, nil
 }
 
-func FillPodfile(podfile *Podfile, threadNum int, printLog bool) {
+// 递归读取target及其子target
+func (s *Podfile) addTarget(dir string, def *p_target_definition, parent *Target) *Target {
+	target := new(Target)
+	target.Name = def.Name
+	target.Abstract = def.Abstract
+	target.Inheritance = def.Inheritance
+	target.Parent = parent
+	target.Depends = generateDepend(dir, def.Dependencies)
+	s.Targets = append(s.Targets, target)
+	for _, child := range def.Children {
+		target.Children = append(target.Children, s.addTarget(dir, child, target))
+	}
+	return target
+}
+
+// 读取本地模块的spec，ctx被取消时不再读取新的spec
+func FillPodfile(ctx context.Context, podfile *Podfile, threadNum int, printLog bool) {
 	if threadNum < 1 {
 		threadNum = 1
 	}
@@ -151,7 +275,7 @@ func FillPodfile(podfile *Podfile, threadNum int, printLog bool) {
 		c <- true
 	}
 	funcAsync := func(d *Depend) {
-		s, e := ReadSpec(d.SpecPath, printLog)
+		s, e := ReadSpecContext(ctx, d.SpecPath, printLog)
 		if e != nil {
 			d.Err = e
 		} else {
@@ -162,10 +286,15 @@ func FillPodfile(podfile *Podfile, threadNum int, printLog bool) {
 	}
 	for _, target := range podfile.Targets {
 		for _, depend := range target.Depends {
-			if len(depend.Type) == 0 {
+			if !depend.IsLocal() {
 				continue
 			}
 			<-c
+			if e := ctx.Err(); e != nil {
+				depend.Err = e
+				c <- true
+				continue
+			}
 			go funcAsync(depend)
 		}
 	}
@@ -226,29 +355,88 @@ func generateDepend(f string, dep []interface{}) []*Depend {
 			if !ok {
 				continue
 			}
-			if len(varr) == 0 {
-				continue
-			}
-			d := varr[0]
-			switch d.(type) {
-			case string:
-				depend.V = d.(string)
-			case map[interface{}]interface{}:
-				x := d.(map[interface{}]interface{})
-				for kk, vv := range x {
-					kkk, okk := kk.(string)
-					vvv, okv := vv.(string)
-					if okk && okv {
-						depend.Type = kkk
-						if len(f) > 0 {
-							depend.SpecPath = path.Join(f, vvv)
-						} else {
-							depend.SpecPath = vvv
-						}
+			for _, d := range varr {
+				switch d.(type) {
+				case string:
+					if depend.V == "" {
+						depend.V = d.(string)
+					} else {
+						depend.V += ", " + d.(string)
 					}
+				case map[interface{}]interface{}:
+					generateDependSource(f, depend, d.(map[interface{}]interface{}))
 				}
 			}
 		}
 	}
 	return res
 }
+
+// 解析:git、:branch、:tag、:commit、:path、:podspec选项
+func generateDependSource(f string, depend *Depend, opts map[interface{}]interface{}) {
+	source := new(DependSource)
+	for kk, vv := range opts {
+		kkk, okk := kk.(string)
+		vvv, okv := vv.(string)
+		if !okk || !okv {
+			continue
+		}
+		switch strings.TrimPrefix(kkk, ":") {
+		case "git":
+			source.Git = vvv
+		case "branch":
+			source.Branch = vvv
+		case "tag":
+			source.Tag = vvv
+		case "commit":
+			source.Commit = vvv
+		case "path":
+			source.Path = vvv
+		case "podspec":
+			source.Podspec = vvv
+		}
+	}
+	switch {
+	case source.Git != "":
+		depend.Type = ":git"
+	case source.Path != "":
+		depend.Type = ":path"
+	case source.Podspec != "":
+		depend.Type = ":podspec"
+	default:
+		return
+	}
+	depend.Source = source
+	if source.Git != "" {
+		return
+	}
+	var p string
+	if source.Path != "" {
+		p = source.Path
+	} else if !strings.Contains(source.Podspec, "://") {
+		p = source.Podspec
+	}
+	if p == "" {
+		return
+	}
+	if len(f) > 0 && !path.IsAbs(p) {
+		p = path.Join(f, p)
+	}
+	depend.SpecPath = localSpecPath(p, BaseModule(depend.N))
+}
+
+// 本地依赖指向目录时，在目录中查找对应的podspec文件
+func localSpecPath(p string, name string) string {
+	if !fs.DirectoryExists(p) {
+		return p
+	}
+	for _, fn := range []string{name + ".podspec", name + ".podspec.json"} {
+		if fs.FileExists(path.Join(p, fn)) {
+			return path.Join(p, fn)
+		}
+	}
+	if matches, e := filepath.Glob(path.Join(p, "*.podspec")); e == nil && len(matches) > 0 {
+		return matches[0]
+	}
+	return p
+}
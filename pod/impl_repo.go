@@ -1,8 +1,10 @@
 package pod
 
 import (
+	"context"
 	"io/ioutil"
 	"path"
+	"sync"
 
 	"errors"
 
@@ -31,7 +33,7 @@ func (s *Pod) Print() {
 	}
 }
 
-func (s *Pod) index(root string, repos []string, filterFunc func(p string, level PodLevel) bool) error {
+func (s *Pod) index(ctx context.Context, root string, repos []string, filterFunc func(p string, level PodLevel) bool, result *IndexResult) error {
 	if !fs.DirectoryExists(root) {
 		return merr.NewErrMessage(merr.ErrCodeNotExist, "Pod根目录不存在["+root+"]")
 	}
@@ -53,20 +55,30 @@ func (s *Pod) index(root string, repos []string, filterFunc func(p string, level
 		repo.Name = rn
 		repo.Root = reporoot
 		podrepos = append(podrepos, repo)
+		result.Repos = append(result.Repos, &IndexRepoStats{Name: rn, Failures: make(map[string]int)})
 	}
 	c := make(chan error, len(podrepos))
 	for _, repo := range podrepos {
-		go goIndexRepo(repo, filterFunc, c)
+		go goIndexRepo(ctx, repo, filterFunc, result, c)
 	}
 	for i := 0; i < len(podrepos); i++ {
-		<-c
+		if e := <-c; e != nil {
+			result.addError(e)
+		}
+	}
+	if e := ctx.Err(); e != nil {
+		return e
 	}
 	s.PodRepos = podrepos
 	return nil
 }
 
 // ** PodRepo Impl **
-func (s *PodRepo) index(filterFunc func(p string, level PodLevel) bool) error {
+// 仓库中没有需要索引的模块时Modules为空，不视为错误
+func (s *PodRepo) index(ctx context.Context, filterFunc func(p string, level PodLevel) bool, result *IndexResult) error {
+	if IsCDNRepo(s.Root) {
+		return s.indexCDN(ctx, filterFunc, result)
+	}
 	dirs, err := ioutil.ReadDir(s.Root)
 	if err != nil {
 		return merr.NewErr(merr.ErrCodeUnknown, err)
@@ -80,6 +92,9 @@ func (s *PodRepo) index(filterFunc func(p string, level PodLevel) bool) error {
 		if f.Name() == ".git" {
 			continue
 		}
+		if e := ctx.Err(); e != nil {
+			return e
+		}
 
 		mp := path.Join(s.Root, f.Name())
 		if filterFunc != nil && filterFunc(mp, ENUM_POD_LEVEL_MODULE) {
@@ -88,20 +103,20 @@ func (s *PodRepo) index(filterFunc func(p string, level PodLevel) bool) error {
 		module := new(PodModule)
 		module.Name = f.Name()
 		module.Root = mp
-		e := module.index(filterFunc)
+		e := module.index(filterFunc, func() {
+			result.AddFailure(s.Name, INDEX_ERR_MISSING_SPEC)
+		})
 		if e == nil {
 			modules = append(modules, module)
 		}
 	}
-	if len(modules) == 0 {
-		return errors.New("Warn: No module in repo " + s.Name + " [" + s.Root + "]")
-	}
 	s.Modules = modules
 	return nil
 }
 
 // ** PodModule Impl **
-func (s *PodModule) index(filterFunc func(p string, level PodLevel) bool) error {
+// 版本目录中没有spec文件时调用onMissingSpec
+func (s *PodModule) index(filterFunc func(p string, level PodLevel) bool, onMissingSpec func()) error {
 	dirs, err := ioutil.ReadDir(s.Root)
 	if err != nil {
 		return merr.NewErr(merr.ErrCodeUnknown, err)
@@ -122,6 +137,8 @@ func (s *PodModule) index(filterFunc func(p string, level PodLevel) bool) error
 		e := version.index()
 		if e == nil {
 			versions = append(versions, version)
+		} else if onMissingSpec != nil {
+			onMissingSpec()
 		}
 	}
 	if len(versions) == 0 {
@@ -155,50 +172,184 @@ func (s *PodModuleVersion) index() error {
 	return nil
 }
 
-// ** Func Public **
-func PodIndex(podRoot string, repos []string, threadNum int, printLog bool, filterFunc func(p string, level PodLevel) bool) (*Pod, int, int, error) {
-	if threadNum < 1 {
-		threadNum = 1
+// ** IndexResult Impl **
+func (s *IndexResult) AddSuccess(repo string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if stats := s.repo(repo); stats != nil {
+		stats.Success++
 	}
-	aPod := new(Pod)
-	err := aPod.index(podRoot, repos, filterFunc)
-	if err != nil {
-		return nil, 0, 0, err
+}
+
+func (s *IndexResult) AddFailure(repo string, category string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if stats := s.repo(repo); stats != nil {
+		stats.Failures[category]++
 	}
-	cPipe := make(chan bool, threadNum)
-	for i := 0; i < threadNum; i++ {
-		cPipe <- true
-	}
-	var success, failure int
-	funcAsyncRead := func(v *PodModuleVersion) {
-		specPath := path.Join(v.Root, v.FileName)
-		aSpec, err := ReadSpec(specPath, printLog)
-		if err == nil {
-			v.Podspec = aSpec
-			success++
-		} else {
-			v.Err = err
-			failure++
-		}
-		cPipe <- true
+}
+
+func (s *IndexResult) AddNoSpec(repo string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if stats := s.repo(repo); stats != nil {
+		stats.NoSpec++
 	}
+}
 
-	for _, repo := range aPod.PodRepos {
-		for _, module := range repo.Modules {
-			for _, version := range module.Versions {
-				<-cPipe
-				go funcAsyncRead(version)
-			}
+func (s *IndexResult) Success() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := 0
+	for _, stats := range s.Repos {
+		n += stats.Success
+	}
+	return n
+}
+
+// category为空时返回全部分类的失败数
+func (s *IndexResult) Failure(category string) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := 0
+	for _, stats := range s.Repos {
+		n += stats.Failure(category)
+	}
+	return n
+}
+
+func (s *IndexResult) addError(e error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.Errors = append(s.Errors, e)
+}
+
+func (s *IndexResult) repo(name string) *IndexRepoStats {
+	for _, stats := range s.Repos {
+		if stats.Name == name {
+			return stats
 		}
 	}
+	return nil
+}
+
+// category为空时返回全部分类的失败数
+func (s *IndexRepoStats) Failure(category string) int {
+	if category != "" {
+		return s.Failures[category]
+	}
+	n := 0
+	for _, c := range s.Failures {
+		n += c
+	}
+	return n
+}
 
+// 待读取的版本数
+func (s *Pod) VersionCount() int {
+	n := 0
+	for _, repo := range s.PodRepos {
+		n += repo.VersionCount()
+	}
+	return n
+}
+
+func (s *PodRepo) VersionCount() int {
+	n := 0
+	for _, module := range s.Modules {
+		n += len(module.Versions)
+	}
+	return n
+}
+
+// ** Func Public **
+// 读取全部spec并挂到对应的PodModuleVersion上，ctx被取消时等待读取中的spec结束后返回ctx.Err()
+func PodIndex(ctx context.Context, podRoot string, repos []string, threadNum int, printLog bool, filterFunc func(p string, level PodLevel) bool) (*Pod, *IndexResult, error) {
+	aPod, result, err := PodWalk(ctx, podRoot, repos, filterFunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	for item := range PodStream(ctx, aPod, result, threadNum, printLog) {
+		item.Version.Podspec = item.Spec
+	}
+	if e := ctx.Err(); e != nil {
+		return nil, result, e
+	}
+	return aPod, result, nil
+}
+
+// 只索引仓库的目录结构，不读取spec；缺少spec文件的版本计入result
+func PodWalk(ctx context.Context, podRoot string, repos []string, filterFunc func(p string, level PodLevel) bool) (*Pod, *IndexResult, error) {
+	aPod := new(Pod)
+	result := new(IndexResult)
+	if err := aPod.index(ctx, podRoot, repos, filterFunc, result); err != nil {
+		return nil, nil, err
+	}
+	return aPod, result, nil
+}
+
+// 使用threadNum个协程读取p中全部版本的spec，按完成顺序发送到返回的channel，读取结果计入result
+// spec不会挂到PodModuleVersion上，失败时设置Version.Err；全部读取完成或ctx被取消后关闭channel
+func PodStream(ctx context.Context, p *Pod, result *IndexResult, threadNum int, printLog bool) <-chan *IndexedSpec {
+	if threadNum < 1 {
+		threadNum = 1
+	}
+	jobs := make(chan *indexJob)
+	out := make(chan *IndexedSpec, threadNum)
+	var wg sync.WaitGroup
 	for i := 0; i < threadNum; i++ {
-		<-cPipe
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				v := job.version
+				if v.FileName == "" {
+					// CDN仓库中尚未下载spec的版本
+					result.AddNoSpec(job.repo)
+					select {
+					case out <- &IndexedSpec{Repo: job.repo, Module: job.module, Version: v}:
+					case <-ctx.Done():
+					}
+					continue
+				}
+				aSpec, err := ReadSpecContext(ctx, path.Join(v.Root, v.FileName), printLog)
+				if err != nil {
+					v.Err = err
+					if ctx.Err() != nil {
+						continue
+					}
+					result.AddFailure(job.repo, SpecErrorCategory(err))
+				} else {
+					result.AddSuccess(job.repo)
+				}
+				select {
+				case out <- &IndexedSpec{Repo: job.repo, Module: job.module, Version: v, Spec: aSpec}:
+				case <-ctx.Done():
+				}
+			}
+		}()
 	}
-	return aPod, success, failure, nil
+	go func() {
+	loop:
+		for _, repo := range p.PodRepos {
+			for _, module := range repo.Modules {
+				for _, version := range module.Versions {
+					select {
+					case jobs <- &indexJob{repo: repo.Name, module: module.Name, version: version}:
+					case <-ctx.Done():
+						break loop
+					}
+				}
+			}
+		}
+		close(jobs)
+		wg.Wait()
+		close(out)
+	}()
+	return out
 }
 
 // ** Func Private **
-func goIndexRepo(repo *PodRepo, filterFunc func(p string, level PodLevel) bool, c chan error) {
-	c <- repo.index(filterFunc)
+func goIndexRepo(ctx context.Context, repo *PodRepo, filterFunc func(p string, level PodLevel) bool, result *IndexResult, c chan error) {
+	c <- repo.index(ctx, filterFunc, result)
 }
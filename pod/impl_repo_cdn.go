@@ -0,0 +1,93 @@
+package pod
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	merr "github.com/go-hayden-base/err"
+)
+
+// CDN仓库(如trunk)的版本列表分片文件，文件名中的 x_y_z 为模块名MD5的前三位，对应 Specs/x/y/z 目录
+const __CDN_VERSIONS_PATTERN = "all_pods_versions_*.txt"
+
+// 仓库根目录下存在版本列表分片文件时视为CocoaPods CDN仓库的本地缓存
+func IsCDNRepo(root string) bool {
+	files, e := filepath.Glob(path.Join(root, __CDN_VERSIONS_PATTERN))
+	return e == nil && len(files) > 0
+}
+
+// 从分片文件读取模块及版本列表，spec取自已下载到 Specs/x/y/z/模块/版本 下的JSON
+// 尚未下载spec的版本FileName为空，作为没有spec的已知版本计入result
+func (s *PodRepo) indexCDN(ctx context.Context, filterFunc func(p string, level PodLevel) bool, result *IndexResult) error {
+	files, err := filepath.Glob(path.Join(s.Root, __CDN_VERSIONS_PATTERN))
+	if err != nil {
+		return merr.NewErr(merr.ErrCodeUnknown, err)
+	}
+	modules := make([]*PodModule, 0, 100)
+	for _, f := range files {
+		if e := ctx.Err(); e != nil {
+			return e
+		}
+		shard := strings.TrimSuffix(strings.TrimPrefix(path.Base(f), "all_pods_versions_"), ".txt")
+		dir := path.Join(append([]string{s.Root, "Specs"}, strings.Split(shard, "_")...)...)
+		list, e := s.indexCDNShard(f, dir, filterFunc)
+		if e != nil {
+			return e
+		}
+		modules = append(modules, list...)
+	}
+	s.Modules = modules
+	return nil
+}
+
+// 分片文件每行为 模块/版本1/版本2/...
+func (s *PodRepo) indexCDNShard(file string, dir string, filterFunc func(p string, level PodLevel) bool) ([]*PodModule, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, merr.NewErr(merr.ErrCodeUnknown, err)
+	}
+	defer f.Close()
+	modules := make([]*PodModule, 0, 10)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		items := strings.Split(strings.TrimSpace(scanner.Text()), "/")
+		if len(items) < 2 || items[0] == "" {
+			continue
+		}
+		mp := path.Join(dir, items[0])
+		if filterFunc != nil && filterFunc(mp, ENUM_POD_LEVEL_MODULE) {
+			continue
+		}
+		module := new(PodModule)
+		module.Name = items[0]
+		module.Root = mp
+		module.Versions = make([]*PodModuleVersion, 0, len(items)-1)
+		for _, v := range items[1:] {
+			if v == "" {
+				continue
+			}
+			vp := path.Join(mp, v)
+			if filterFunc != nil && filterFunc(vp, ENUM_POD_LEVEL_VERSION) {
+				continue
+			}
+			version := new(PodModuleVersion)
+			version.Name = v
+			version.Root = vp
+			// 未下载spec时FileName为空
+			version.index()
+			module.Versions = append(module.Versions, version)
+		}
+		if len(module.Versions) > 0 {
+			modules = append(modules, module)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, merr.NewErr(merr.ErrCodeUnknown, err)
+	}
+	return modules, nil
+}
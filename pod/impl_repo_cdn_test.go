@@ -0,0 +1,118 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, p string, content string) {
+	t.Helper()
+	if e := os.MkdirAll(filepath.Dir(p), 0755); e != nil {
+		t.Fatal(e)
+	}
+	if e := os.WriteFile(p, []byte(content), 0644); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func testSpecJSON(module string, version string) string {
+	return fmt.Sprintf(`{"name": %q, "version": %q}`, module, version)
+}
+
+// 构造两个CDN仓库:
+// cdn中D的1.0已下载spec，2.0未下载；E的1.0被过滤；Skip模块被过滤；分片中的空行及缺少版本的行被忽略
+// broken中一个分片文件无法读取
+func makeTestCDNRoot(t *testing.T) string {
+	root := t.TempDir()
+	cdn := filepath.Join(root, "cdn")
+	writeTestFile(t, filepath.Join(cdn, "all_pods_versions_0_0_0.txt"), "D/1.0/2.0\n\nNoVersion\nSkip/1.0\n")
+	writeTestFile(t, filepath.Join(cdn, "all_pods_versions_a_b_c.txt"), "E/1.0/1.1/\n")
+	writeTestFile(t, filepath.Join(cdn, "Specs", "0", "0", "0", "D", "1.0", "D.podspec.json"), testSpecJSON("D", "1.0"))
+	writeTestFile(t, filepath.Join(cdn, "Specs", "a", "b", "c", "E", "1.1", "E.podspec.json"), testSpecJSON("E", "1.1"))
+
+	broken := filepath.Join(root, "broken")
+	writeTestFile(t, filepath.Join(broken, "all_pods_versions_0_0_0.txt"), "F/1.0\n")
+	if e := os.MkdirAll(filepath.Join(broken, "all_pods_versions_1_1_1.txt"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	return root
+}
+
+func cdnFilter(p string, level PodLevel) bool {
+	switch level {
+	case ENUM_POD_LEVEL_MODULE:
+		return path.Base(p) == "Skip"
+	case ENUM_POD_LEVEL_VERSION:
+		return path.Base(path.Dir(p)) == "E" && path.Base(p) == "1.0"
+	}
+	return false
+}
+
+func TestIsCDNRepo(t *testing.T) {
+	root := makeTestCDNRoot(t)
+	if !IsCDNRepo(filepath.Join(root, "cdn")) {
+		t.Fatal("cdn应为CDN仓库")
+	}
+	if IsCDNRepo(filepath.Join(root, "cdn", "Specs")) {
+		t.Fatal("Specs不应为CDN仓库")
+	}
+}
+
+func TestPodWalkCDN(t *testing.T) {
+	root := makeTestCDNRoot(t)
+	ctx := context.Background()
+	aPod, result, err := PodWalk(ctx, root, []string{"cdn", "broken"}, cdnFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 1 {
+		t.Fatalf("仓库错误数为 %d: %v", len(result.Errors), result.Errors)
+	}
+	versions := make(map[string]string)
+	for _, repo := range aPod.PodRepos {
+		for _, module := range repo.Modules {
+			for _, v := range module.Versions {
+				versions[repo.Name+"/"+module.Name+"/"+v.Name] = v.FileName
+			}
+		}
+	}
+	expect := map[string]string{
+		"cdn/D/1.0": "D.podspec.json",
+		"cdn/D/2.0": "",
+		"cdn/E/1.1": "E.podspec.json",
+	}
+	if len(versions) != len(expect) {
+		t.Fatalf("索引的版本为 %v", versions)
+	}
+	for k, v := range expect {
+		if fileName, ok := versions[k]; !ok || fileName != v {
+			t.Fatalf("%s: FileName为 %q", k, fileName)
+		}
+	}
+
+	specs := 0
+	for item := range PodStream(ctx, aPod, result, 4, false) {
+		if item.Spec != nil {
+			specs++
+		}
+	}
+	if specs != 2 {
+		t.Fatalf("读取成功的spec数为 %d", specs)
+	}
+	for _, stats := range result.Repos {
+		switch stats.Name {
+		case "cdn":
+			if stats.Success != 2 || stats.NoSpec != 1 || stats.Failure("") != 0 {
+				t.Fatalf("cdn: 成功 %d 无spec %d 失败 %v", stats.Success, stats.NoSpec, stats.Failures)
+			}
+		case "broken":
+			if stats.Success != 0 || stats.NoSpec != 0 {
+				t.Fatalf("broken: 成功 %d 无spec %d", stats.Success, stats.NoSpec)
+			}
+		}
+	}
+}
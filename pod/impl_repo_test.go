@@ -0,0 +1,116 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+)
+
+// 构造两个普通仓库:
+// normal中20个模块各3个版本，另有解析失败、缺少spec文件及被过滤的模块
+// other中5个模块各2个版本
+func makeTestPodRoot(t *testing.T) string {
+	root := t.TempDir()
+	normal := filepath.Join(root, "normal")
+	for i := 0; i < 20; i++ {
+		module := fmt.Sprintf("M%02d", i)
+		for _, version := range []string{"1.0", "1.1", "2.0"} {
+			writeTestFile(t, filepath.Join(normal, module, version, module+".podspec.json"), testSpecJSON(module, version))
+		}
+	}
+	writeTestFile(t, filepath.Join(normal, "Bad", "1.0", "Bad.podspec.json"), "{")
+	writeTestFile(t, filepath.Join(normal, "Bad", "2.0", "Bad.podspec.json"), testSpecJSON("Bad", "2.0"))
+	if e := os.MkdirAll(filepath.Join(normal, "Bad", "3.0"), 0755); e != nil {
+		t.Fatal(e)
+	}
+	writeTestFile(t, filepath.Join(normal, "Skip", "1.0", "Skip.podspec.json"), testSpecJSON("Skip", "1.0"))
+
+	other := filepath.Join(root, "other")
+	for i := 0; i < 5; i++ {
+		module := fmt.Sprintf("O%d", i)
+		for _, version := range []string{"1.0", "2.0"} {
+			writeTestFile(t, filepath.Join(other, module, version, module+".podspec.json"), testSpecJSON(module, version))
+		}
+	}
+	return root
+}
+
+func skipFilter(p string, level PodLevel) bool {
+	return level == ENUM_POD_LEVEL_MODULE && path.Base(p) == "Skip"
+}
+
+func TestPodWalkStream(t *testing.T) {
+	root := makeTestPodRoot(t)
+	ctx := context.Background()
+	aPod, result, err := PodWalk(ctx, root, []string{"normal", "other"}, skipFilter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Errors) != 0 {
+		t.Fatalf("仓库错误: %v", result.Errors)
+	}
+	if n := aPod.VersionCount(); n != 20*3+2+5*2 {
+		t.Fatalf("待读取版本数为 %d", n)
+	}
+
+	specs := make(map[string]bool)
+	for item := range PodStream(ctx, aPod, result, 8, false) {
+		if item.Spec != nil {
+			specs[item.Repo+"/"+item.Module+"/"+item.Spec.Version] = true
+		}
+	}
+	if len(specs) != 20*3+1+5*2 {
+		t.Fatalf("读取成功的spec数为 %d", len(specs))
+	}
+	if !specs["other/O4/2.0"] || specs["normal/Skip/1.0"] {
+		t.Fatal("读取的spec不符")
+	}
+
+	expect := map[string]*IndexRepoStats{
+		"normal": {Success: 61, Failures: map[string]int{INDEX_ERR_PARSE_FAILURE: 1, INDEX_ERR_MISSING_SPEC: 1}},
+		"other":  {Success: 10, Failures: map[string]int{}},
+	}
+	if len(result.Repos) != len(expect) {
+		t.Fatalf("仓库数为 %d", len(result.Repos))
+	}
+	for _, stats := range result.Repos {
+		e := expect[stats.Name]
+		if stats.Success != e.Success || stats.NoSpec != e.NoSpec || stats.Failure("") != e.Failure("") {
+			t.Fatalf("%s: 成功 %d 无spec %d 失败 %v", stats.Name, stats.Success, stats.NoSpec, stats.Failures)
+		}
+		for category, n := range e.Failures {
+			if stats.Failure(category) != n {
+				t.Fatalf("%s: %s为 %d", stats.Name, category, stats.Failure(category))
+			}
+		}
+	}
+	if result.Success() != 71 || result.Failure("") != 2 {
+		t.Fatalf("成功 %d 失败 %d", result.Success(), result.Failure(""))
+	}
+}
+
+func TestPodIndexCancel(t *testing.T) {
+	root := makeTestPodRoot(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	aPod, result, err := PodWalk(ctx, root, []string{"normal"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range PodStream(ctx, aPod, result, 4, false) {
+		if n++; n == 5 {
+			cancel()
+		}
+	}
+	if ctx.Err() == nil || n >= aPod.VersionCount() {
+		t.Fatalf("取消后仍读取了 %d 个版本", n)
+	}
+
+	if _, _, err = PodIndex(ctx, root, []string{"normal"}, 4, false, nil); err != context.Canceled {
+		t.Fatalf("ctx取消: %v", err)
+	}
+}
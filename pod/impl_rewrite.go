@@ -0,0 +1,198 @@
+package pod
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const __REG_PF_POD_LINE = `^(\s*pod\s*\(?\s*)(['"])([^'"]+)(['"])(.*)$`
+const __REG_PF_REQUIREMENT = `^(\s*,\s*)(['"])([^'"]*)(['"])`
+const __REG_PF_EXTERNAL = `(:(path|podspec|git)\s*=>)|(\b(path|podspec|git):)`
+
+// ** Podfile Rewrite **
+
+// 读取Podfile首个target之前的内容作为Header，最后一个end之后的内容作为Footer
+func (s *Podfile) readSections() error {
+	b, e := ioutil.ReadFile(s.FilePath)
+	if e != nil {
+		return e
+	}
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	headerEnd, footerStart := -1, -1
+	offset := 0
+	for _, line := range lines {
+		l := string(line)
+		if _, ok := CheckTarget(l); ok && headerEnd < 0 {
+			headerEnd = offset
+		}
+		offset += len(line)
+		if IsEnd(strings.TrimRight(l, "\r\n")) {
+			footerStart = offset
+		}
+	}
+	if headerEnd < 0 {
+		s.Header = b
+		s.Footer = nil
+		return nil
+	}
+	s.Header = b[:headerEnd]
+	if footerStart > headerEnd {
+		s.Footer = b[footerStart:]
+	}
+	return nil
+}
+
+// 按versions(模块名->版本)改写Podfile中pod行的版本要求，注释、顺序和格式保持不变；
+// 含有:path、:podspec、:git的pod行不会被改写；implicits会追加在首个target之前。
+// 跨多行的pod声明及含多个版本要求的pod行不会被改写，记录在返回的skipped中。
+// 返回改写后的内容、被改写或追加的模块名以及未改写的pod行
+func (s *Podfile) Rewrite(versions map[string]string, implicits []*DependBase) ([]byte, []string, []*RewriteSkip, error) {
+	b, e := ioutil.ReadFile(s.FilePath)
+	if e != nil {
+		return nil, nil, nil, e
+	}
+	regLine := regexp.MustCompile(__REG_PF_POD_LINE)
+	regReq := regexp.MustCompile(__REG_PF_REQUIREMENT)
+	regExt := regexp.MustCompile(__REG_PF_EXTERNAL)
+
+	changed := make([]string, 0, len(versions))
+	skipped := make([]*RewriteSkip, 0, 2)
+	var buffer bytes.Buffer
+	offset := 0
+	insertAt := len(s.Header)
+	if !bytes.HasPrefix(b, s.Header) {
+		insertAt = 0
+	}
+	inserted := len(implicits) == 0
+	insert := func() {
+		buffer.Write(implicitLines(implicits))
+		for _, aDep := range implicits {
+			changed = append(changed, aDep.N)
+		}
+		inserted = true
+	}
+	lines := bytes.SplitAfter(b, []byte("\n"))
+	for _, line := range lines {
+		if !inserted && offset == insertAt {
+			insert()
+		}
+		offset += len(line)
+		l := string(line)
+		m := regLine.FindStringSubmatch(strings.TrimRight(l, "\r\n"))
+		if m == nil {
+			buffer.Write(line)
+			continue
+		}
+		name := m[3]
+		v, ok := versions[name]
+		if !ok {
+			v, ok = versions[BaseModule(name)]
+		}
+		code, _ := splitComment(m[5])
+		if !ok || v == "" || v == "*" || regExt.MatchString(code) {
+			buffer.Write(line)
+			continue
+		}
+		if continuesOnNextLine(code) {
+			skipped = append(skipped, &RewriteSkip{Name: name, Version: v, Reason: "pod声明跨多行"})
+			buffer.Write(line)
+			continue
+		}
+		quote := m[2]
+		rest := m[5]
+		sep := ", "
+		reqs := make([]string, 0, 2)
+		for {
+			rm := regReq.FindStringSubmatch(rest)
+			if rm == nil || strings.HasPrefix(strings.TrimSpace(rest[len(rm[0]):]), "=>") {
+				break
+			}
+			if len(reqs) == 0 {
+				sep = rm[1]
+			}
+			reqs = append(reqs, rm[3])
+			rest = rest[len(rm[0]):]
+		}
+		if len(reqs) > 1 {
+			skipped = append(skipped, &RewriteSkip{Name: name, Version: v, Reason: "含多个版本要求: " + strings.Join(reqs, ", ")})
+			buffer.Write(line)
+			continue
+		}
+		if len(reqs) == 1 && strings.TrimSpace(reqs[0]) == v {
+			buffer.Write(line)
+			continue
+		}
+		buffer.WriteString(m[1] + m[2] + name + m[4] + sep + quote + v + quote + rest)
+		buffer.WriteString(l[len(strings.TrimRight(l, "\r\n")):])
+		changed = append(changed, name)
+	}
+	if !inserted {
+		insert()
+	}
+	return buffer.Bytes(), changed, skipped, nil
+}
+
+// pod声明在下一行继续：以逗号或反斜杠结尾，或括号未闭合
+func continuesOnNextLine(code string) bool {
+	code = strings.TrimSpace(code)
+	if strings.HasSuffix(code, ",") || strings.HasSuffix(code, "\\") {
+		return true
+	}
+	var quote rune
+	depth := 0
+	for _, c := range code {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+	}
+	return depth > 0
+}
+
+func implicitLines(implicits []*DependBase) []byte {
+	sorted := make([]*DependBase, len(implicits))
+	copy(sorted, implicits)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].N < sorted[j].N
+	})
+	var buffer bytes.Buffer
+	buffer.WriteString("#Implicit\n")
+	for _, aDep := range sorted {
+		if aDep.V != "" && aDep.V != "*" {
+			buffer.WriteString("pod '" + aDep.N + "', '" + aDep.V + "'\n")
+		} else {
+			buffer.WriteString("pod '" + aDep.N + "'\n")
+		}
+	}
+	buffer.WriteString("\n")
+	return buffer.Bytes()
+}
+
+// 拆分代码与行尾注释，忽略引号内的#
+func splitComment(line string) (string, string) {
+	var quote rune
+	for idx, c := range line {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '#':
+			return line[:idx], line[idx:]
+		}
+	}
+	return line, ""
+}
@@ -1,9 +1,9 @@
 package pod
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
-	"os/exec"
 	"path"
 	"strings"
 
@@ -68,6 +68,11 @@ func (s *Spec) enumerateDepends(f func(module, depend, version string)) {
 	}
 }
 
+// 返回spec及全部子spec声明的依赖
+func (s *Spec) AllDepends() []*DependBase {
+	return getAllDependsFromSpec(s)
+}
+
 func (s *Spec) IsDefaultSpec(name string) bool {
 	if s.DefaultSpecs == nil {
 		return true
@@ -240,36 +245,65 @@ func getPathDepends(specs []*Spec) map[string]string {
 
 // ** Public Func **
 func ReadSpec(filePath string, printLog bool) (*Spec, error) {
+	return ReadSpecContext(context.Background(), filePath, printLog)
+}
+
+// .podspec按SetSpecTimeout设置的超时时间求值，超时时IsEvaluateTimeout返回true
+// 读取失败时返回*SpecError，ctx被取消时返回ctx.Err()
+func ReadSpecContext(ctx context.Context, filePath string, printLog bool) (*Spec, error) {
 	printIfNeed(printLog, "解析Spec文件: "+filePath+" ... ")
 	if len(filePath) == 0 || !fs.FileExists(filePath) {
-		return nil, errors.New("请正确指定spec文件！")
+		return nil, &SpecError{INDEX_ERR_MISSING_SPEC, errors.New("请正确指定spec文件！")}
 	}
 	ext := strings.ToLower(path.Ext(filePath))
 	if ext != ".json" && ext != ".podspec" {
-		return nil, errors.New("spec 文件格式不正确！")
+		return nil, &SpecError{INDEX_ERR_PARSE_FAILURE, errors.New("spec 文件格式不正确！")}
 	}
-	var b []byte
-	var err error
-	if ext == ".json" {
-		b, err = ioutil.ReadFile(filePath)
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		b, err = exec.Command("pod", "ipc", "spec", filePath).Output()
+	b, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return nil, &SpecError{INDEX_ERR_PARSE_FAILURE, err}
+	}
+	if ext == ".podspec" {
+		b, err = evaluateSpecWithCache(b, func() ([]byte, error) {
+			return evaluateSpec(ctx, filePath)
+		})
 		if err != nil {
-			return nil, err
+			if err == ctx.Err() {
+				return nil, err
+			}
+			return nil, &SpecError{INDEX_ERR_EVALUATOR_FAILURE, err}
 		}
 	}
 
 	if spec, err := NewSpecWithJSONBytes(b); err != nil {
-		return nil, err
+		return nil, &SpecError{INDEX_ERR_PARSE_FAILURE, err}
 	} else {
 		spec.FilePath = filePath
 		return spec, nil
 	}
 }
 
+// ** SpecError Impl **
+func (s *SpecError) Error() string {
+	return s.Err.Error()
+}
+
+// 错误的索引失败分类，非*SpecError时视为解析失败
+func SpecErrorCategory(err error) string {
+	if e, ok := err.(*SpecError); ok {
+		return e.Category
+	}
+	return INDEX_ERR_PARSE_FAILURE
+}
+
+// 是否为spec求值超时
+func IsEvaluateTimeout(err error) bool {
+	if e, ok := err.(*SpecError); ok {
+		err = e.Err
+	}
+	return err == ErrEvaluateTimeout
+}
+
 func NewSpecWithJSONString(json string) (*Spec, error) {
 	return NewSpecWithJSONBytes([]byte(json))
 }
@@ -0,0 +1,47 @@
+package pod
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sync/atomic"
+)
+
+var (
+	_SpecCache     SpecCache
+	_SpecCacheHit  int64
+	_SpecCacheMiss int64
+)
+
+// 设置podspec求值缓存，PodIndex、FillPodfile及ReadSpec共用；为nil时不使用缓存
+func SetSpecCache(c SpecCache) {
+	_SpecCache = c
+}
+
+// 自进程启动以来的缓存命中统计
+func GetSpecCacheStats() SpecCacheStats {
+	return SpecCacheStats{Hit: atomic.LoadInt64(&_SpecCacheHit), Miss: atomic.LoadInt64(&_SpecCacheMiss)}
+}
+
+func SpecContentHash(b []byte) string {
+	sum := sha1.Sum(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// 优先从缓存读取求值结果，未命中时调用evaluate并写入缓存
+func evaluateSpecWithCache(content []byte, evaluate func() ([]byte, error)) ([]byte, error) {
+	if _SpecCache == nil {
+		return evaluate()
+	}
+	hash := SpecContentHash(content)
+	if b, ok := _SpecCache.Get(hash); ok {
+		atomic.AddInt64(&_SpecCacheHit, 1)
+		return b, nil
+	}
+	atomic.AddInt64(&_SpecCacheMiss, 1)
+	b, err := evaluate()
+	if err != nil {
+		return nil, err
+	}
+	_SpecCache.Put(hash, b)
+	return b, nil
+}
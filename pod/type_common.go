@@ -5,6 +5,7 @@ type IDepend interface {
 	Name() string
 	Subdepends() []*DependBase
 	IsLocal() bool
+	ExternalSource() *DependSource
 }
 
 type DependBase struct {
@@ -28,6 +29,10 @@ func (s *DependBase) IsLocal() bool {
 	return false
 }
 
+func (s *DependBase) ExternalSource() *DependSource {
+	return nil
+}
+
 func (s *DependBase) String() string {
 	return "[" + s.N + ":" + s.V + "]"
 }
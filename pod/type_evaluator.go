@@ -0,0 +1,37 @@
+package pod
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os/exec"
+)
+
+// .podspec求值，返回spec的JSON；ctx结束时应尽快返回ctx.Err()
+type SpecEvaluator interface {
+	Evaluate(ctx context.Context, filePath string) ([]byte, error)
+	Close() error
+}
+
+// 每个spec执行一次pod ipc spec
+type ipcEvaluator struct{}
+
+// 常驻进程求值，多个spec复用同一进程，避免每次启动Ruby及CocoaPods的开销
+type ProcessEvaluator struct {
+	workers chan *evalProcess
+}
+
+// 常驻进程，同一时间只处理一个请求
+type evalProcess struct {
+	command []string
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	pipe    io.ReadCloser
+	stdout  *bufio.Reader
+	seq     int
+}
+
+type evalResponse struct {
+	b   []byte
+	err error
+}
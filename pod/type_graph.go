@@ -10,5 +10,41 @@ type GraphModule struct {
 	IsCommon        bool
 	IsNew           bool
 	IsLocal         bool
+	Source          *DependSource
 	Depends         []*DependBase
 }
+
+type GraphModuleDiff struct {
+	Name string
+	A    *GraphModule
+	B    *GraphModule
+}
+
+// 用于导出(DOT等)的依赖图
+type GraphExport struct {
+	Nodes []*GraphNode
+	Edges []*GraphEdge
+}
+
+type GraphNode struct {
+	Name       string
+	Version    string
+	UpgradeTo  string
+	UpgradeTag string
+	IsCommon   bool
+	IsNew      bool
+	IsLocal    bool
+	Depth      int
+}
+
+type GraphEdge struct {
+	From  string
+	To    string
+	Label string
+}
+
+type GraphExportOption struct {
+	CollapseSubspecs bool
+	MaxDepth         int
+	MaxNodes         int
+}
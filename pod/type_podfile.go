@@ -2,14 +2,26 @@ package pod
 
 type Podfile struct {
 	FilePath string
+	Sources  []string
 	Header   []byte
+	Root     *Target
 	Targets  []*Target
 	Footer   []byte
 }
 
+const (
+	TARGET_INHERITANCE_COMPLETE     = "complete"
+	TARGET_INHERITANCE_NONE         = "none"
+	TARGET_INHERITANCE_SEARCH_PATHS = "search_paths"
+)
+
 type Target struct {
-	Name    string
-	Depends []*Depend
+	Name        string
+	Abstract    bool
+	Inheritance string
+	Parent      *Target
+	Children    []*Target
+	Depends     []*Depend
 }
 
 type Depend struct {
@@ -17,22 +29,37 @@ type Depend struct {
 	SpecPath    string
 	SpecDepends []*DependBase
 	Type        string
+	Source      *DependSource
 	Err         error
 }
 
+// Rewrite未改写的pod行及原因
+type RewriteSkip struct {
+	Name    string
+	Version string
+	Reason  string
+}
+
+// pod行中:git、:path、:podspec等外部来源
+type DependSource struct {
+	Git     string
+	Branch  string
+	Tag     string
+	Commit  string
+	Path    string
+	Podspec string
+}
+
 // *** Private ***
 type p_podfile struct {
+	Sources            []string
 	Target_definitions []*p_target_definition
 }
 
 type p_target_definition struct {
 	Abstract     bool
-	Children     []*p_target
-	Dependencies []interface{}
-	Name         string
-}
-
-type p_target struct {
+	Inheritance  string
+	Children     []*p_target_definition
 	Dependencies []interface{}
 	Name         string
 }
@@ -1,5 +1,7 @@
 package pod
 
+import "sync"
+
 // Type Define
 type Pod struct {
 	PodRepos []*PodRepo
@@ -22,8 +24,53 @@ type PodModule struct {
 
 type PodModuleVersion struct {
 	PodBase
-	Source   string
+	Source string
+	// CDN仓库中尚未下载spec的版本为空
 	FileName string
 	Podspec  *Spec
 	Err      error
 }
+
+// 索引失败的分类
+const (
+	INDEX_ERR_MISSING_SPEC      = "missing_spec"
+	INDEX_ERR_PARSE_FAILURE     = "parse_failure"
+	INDEX_ERR_EVALUATOR_FAILURE = "evaluator_failure"
+	INDEX_ERR_DUPLICATE_KEY     = "duplicate_key"
+)
+
+// 读取spec的错误，Category为索引失败的分类
+type SpecError struct {
+	Category string
+	Err      error
+}
+
+// 索引结果统计，并发安全
+type IndexResult struct {
+	Repos []*IndexRepoStats
+	// 仓库级别的错误，例如仓库目录无法读取
+	Errors []error
+	lock   sync.Mutex
+}
+
+type IndexRepoStats struct {
+	Name    string
+	Success int
+	// CDN仓库中没有spec的已知版本数
+	NoSpec   int
+	Failures map[string]int
+}
+
+// 流式索引中读取完成的一个版本，Version.Err不为nil时Spec为nil
+type IndexedSpec struct {
+	Repo    string
+	Module  string
+	Version *PodModuleVersion
+	Spec    *Spec
+}
+
+type indexJob struct {
+	repo    string
+	module  string
+	version *PodModuleVersion
+}
@@ -28,3 +28,14 @@ type SpecSource struct {
 }
 
 type SpecDenpendence map[string][]string
+
+// podspec求值结果缓存，key为podspec文件内容的哈希
+type SpecCache interface {
+	Get(hash string) ([]byte, bool)
+	Put(hash string, b []byte)
+}
+
+type SpecCacheStats struct {
+	Hit  int64
+	Miss int64
+}
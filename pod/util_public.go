@@ -192,6 +192,19 @@ func MatchVersionConstraint(constraint string, version string) bool {
 	return aConstraint.Check(aVersion)
 }
 
+// to的主版本号大于from时返回true，无法解析的版本号视为非主版本升级
+func IsMajorUpgrade(from string, to string) bool {
+	aVerFrom, e := ver.NewVersion(from)
+	if e != nil {
+		return false
+	}
+	aVerTo, e := ver.NewVersion(to)
+	if e != nil {
+		return false
+	}
+	return aVerTo.Segments()[0] > aVerFrom.Segments()[0]
+}
+
 func ContainsString(src []string, s string) bool {
 	c := false
 	for _, itme := range src {
@@ -0,0 +1,187 @@
+package store
+
+// ** Query **
+const _SQL_QUERY_EXIST_KEY = `SELECT key FROM repo`
+
+const _SQL_QUERY_SPEC = `SELECT key, repo, module, version, path, spec_json, ctime FROM repo WHERE module=? AND version=?`
+
+const _SQL_QUERY_VERSIONS = `SELECT repo, version FROM repo WHERE module=? AND spec_json<>''`
+
+const _SQL_QUERY_MODULES = `SELECT DISTINCT module FROM repo WHERE module LIKE ? ORDER BY module LIMIT ?`
+
+const _SQL_QUERY_SEARCH_SPEC = `SELECT key, repo, module, version, path, spec_json, ctime FROM repo WHERE spec_json LIKE ?`
+
+const _SQL_QUERY_LAST_SYNC = `SELECT sync_time FROM updatelog WHERE status=? ORDER BY sync_time DESC LIMIT 1`
+
+const _SQL_QUERY_INDEX = `SELECT key, repo, module, version, path, ctime, mtime FROM repo`
+
+const _SQL_SYNC_LOG_COLUMNS = `SELECT rowid, sync_time, status, duration_ms, added, changed, removed, failed, pandora_version, config_hash FROM updatelog `
+
+const _SQL_QUERY_LAST_SYNC_LOG = _SQL_SYNC_LOG_COLUMNS + `ORDER BY rowid DESC LIMIT 1`
+
+const _SQL_QUERY_SYNC_LOGS = _SQL_SYNC_LOG_COLUMNS + `ORDER BY rowid DESC LIMIT ?`
+
+const _SQL_QUERY_SYNC_LOG = _SQL_SYNC_LOG_COLUMNS + `WHERE rowid=?`
+
+const _SQL_QUERY_REPO_STATS = `SELECT sync_id, sync_time, repo, success, missing_spec, parse_failure, evaluator_failure, duplicate_key, git_head FROM sync_repo_stats WHERE sync_id=? ORDER BY repo`
+
+const _SQL_QUERY_CHANGES = `SELECT sync_id, repo, module, version, action FROM sync_change WHERE sync_id=? ORDER BY action, repo, module, version`
+
+const _SQL_QUERY_CHECKPOINTS = `SELECT sync_id, repo, module FROM sync_checkpoint WHERE sync_id=?`
+
+const _SQL_QUERY_FAILURES = `SELECT key, repo, module, version, path, category, error, ctime FROM spec_failure ORDER BY repo, module, version`
+
+const _SQL_QUERY_SPEC_CACHE = `SELECT spec_json FROM spec_cache WHERE hash=?`
+
+const _SQL_COUNT_SPEC_CACHE = `SELECT COUNT(*) FROM spec_cache`
+
+// ** Insert **
+const _SQL_INSERT_REPO = `
+INSERT INTO repo (key, repo, module, version, path, spec_json, ctime, mtime)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+const _SQL_REPLACE_REPO = `
+INSERT OR REPLACE INTO repo (key, repo, module, version, path, spec_json, ctime, mtime)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+const _SQL_INSERT_FAILURE = `
+INSERT OR REPLACE INTO spec_failure (key, repo, module, version, path, category, error, ctime)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+const _SQLINSERT_LOG = `
+INSERT INTO updatelog (sync_time, status, pandora_version, config_hash) VALUES (?, ?, ?, ?)
+`
+
+const _SQL_INSERT_CHANGE = `
+INSERT INTO sync_change (sync_id, repo, module, version, action) VALUES (?, ?, ?, ?, ?)
+`
+
+const _SQL_INSERT_CHECKPOINT = `
+INSERT OR IGNORE INTO sync_checkpoint (sync_id, repo, module) VALUES (?, ?, ?)
+`
+
+const _SQL_INSERT_REPO_STATS = `
+INSERT INTO sync_repo_stats (sync_id, sync_time, repo, success, missing_spec, parse_failure, evaluator_failure, duplicate_key, git_head)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+`
+
+const _SQL_INSERT_SPEC_CACHE = `
+INSERT OR REPLACE INTO spec_cache (hash, spec_json, ctime) VALUES (?, ?, ?)
+`
+
+// ** Update **
+const _SQL_UPDATE_LOG = `
+UPDATE updatelog SET status=?, duration_ms=?, added=?, changed=?, removed=?, failed=? WHERE rowid=?
+`
+
+const _SQL_TOUCH_REPO = `UPDATE repo SET mtime=? WHERE key=?`
+
+// ** Delete **
+const _SQL_CLEAR_SPEC_CACHE = `DELETE FROM spec_cache`
+
+const _SQL_DELETE_FAILURE = `DELETE FROM spec_failure WHERE key=?`
+
+const _SQL_DELETE_REPO = `DELETE FROM repo WHERE key=?`
+
+// ** Create Table ***
+const _SQL_REPO_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS repo (
+	key        TEXT NOT NULL PRIMARY KEY,
+	repo       TEXT NOT NULL,
+	module     TEXT NOT NULL,
+	version    TEXT NOT NULL,
+	path       TEXT NOT NULL,
+	spec_json  TEXT,
+	ctime      datetime,
+	mtime      datetime
+)
+`
+
+const _SQL_SPEC_FAILURE_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS spec_failure (
+	key        TEXT NOT NULL PRIMARY KEY,
+	repo       TEXT NOT NULL,
+	module     TEXT NOT NULL,
+	version    TEXT NOT NULL,
+	path       TEXT NOT NULL,
+	category   TEXT NOT NULL,
+	error      TEXT NOT NULL,
+	ctime      datetime
+)
+`
+
+const _SQL_REPO_SYNCLOG_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS updatelog (
+	sync_time        datetime,
+	status           TEXT NOT NULL DEFAULT 'completed',
+	duration_ms      INTEGER NOT NULL DEFAULT 0,
+	added            INTEGER NOT NULL DEFAULT 0,
+	changed          INTEGER NOT NULL DEFAULT 0,
+	removed          INTEGER NOT NULL DEFAULT 0,
+	failed           INTEGER NOT NULL DEFAULT 0,
+	pandora_version  TEXT NOT NULL DEFAULT '',
+	config_hash      TEXT NOT NULL DEFAULT ''
+)
+`
+
+const _SQL_SYNC_CHANGE_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS sync_change (
+	sync_id    INTEGER NOT NULL,
+	repo       TEXT NOT NULL,
+	module     TEXT NOT NULL,
+	version    TEXT NOT NULL,
+	action     TEXT NOT NULL
+)
+`
+
+const _SQL_SYNC_CHANGE_INDEX_CREATE = `CREATE INDEX IF NOT EXISTS sync_change_sync_id ON sync_change (sync_id)`
+
+const _SQL_SYNC_CHECKPOINT_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS sync_checkpoint (
+	sync_id    INTEGER NOT NULL,
+	repo       TEXT NOT NULL,
+	module     TEXT NOT NULL,
+	PRIMARY KEY (sync_id, repo, module)
+)
+`
+
+const _SQL_REPO_STATS_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS sync_repo_stats (
+	sync_id            INTEGER,
+	sync_time          datetime,
+	repo               TEXT NOT NULL,
+	success            INTEGER NOT NULL DEFAULT 0,
+	missing_spec       INTEGER NOT NULL DEFAULT 0,
+	parse_failure      INTEGER NOT NULL DEFAULT 0,
+	evaluator_failure  INTEGER NOT NULL DEFAULT 0,
+	duplicate_key      INTEGER NOT NULL DEFAULT 0,
+	git_head           TEXT NOT NULL DEFAULT ''
+)
+`
+
+const _SQL_SPEC_CACHE_TB_CREATE = `
+CREATE TABLE IF NOT EXISTS spec_cache (
+	hash       TEXT NOT NULL PRIMARY KEY,
+	spec_json  TEXT NOT NULL,
+	ctime      datetime
+)
+`
+
+// ** Migrate **
+// 为旧版本创建的表补充字段，字段已存在时忽略错误
+var _SQL_MIGRATIONS = []string{
+	`ALTER TABLE updatelog ADD COLUMN status TEXT NOT NULL DEFAULT 'completed'`,
+	`ALTER TABLE sync_repo_stats ADD COLUMN sync_id INTEGER`,
+	`ALTER TABLE sync_repo_stats ADD COLUMN git_head TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE updatelog ADD COLUMN duration_ms INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE updatelog ADD COLUMN added INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE updatelog ADD COLUMN changed INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE updatelog ADD COLUMN removed INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE updatelog ADD COLUMN failed INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE updatelog ADD COLUMN pandora_version TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE updatelog ADD COLUMN config_hash TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE repo ADD COLUMN mtime datetime`,
+}
@@ -0,0 +1,452 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 内存存储，进程退出后数据丢失，用于临时索引及测试
+type MemoryStore struct {
+	lock        sync.RWMutex
+	records     []*Record
+	keys        map[string]bool
+	syncs       []*SyncLog
+	syncID      int64
+	changes     []*Change
+	checkpoints []*Checkpoint
+	// 已记录的检查点，与SQLite的INSERT OR IGNORE一致，重复的检查点忽略
+	checkpointKeys map[Checkpoint]bool
+	stats          []*RepoStats
+	failures       map[string]*Failure
+	cache          map[string]string
+}
+
+func NewMemory() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]bool), checkpointKeys: make(map[Checkpoint]bool), failures: make(map[string]*Failure), cache: make(map[string]string)}
+}
+
+func (s *MemoryStore) ListVersions(ctx context.Context, module string) ([]*Record, error) {
+	return s.filter(ctx, func(r *Record) bool {
+		return r.Module == module && r.SpecJSON != ""
+	})
+}
+
+func (s *MemoryStore) GetSpecs(ctx context.Context, module string, version string) ([]*Record, error) {
+	return s.filter(ctx, func(r *Record) bool {
+		return r.Module == module && r.Version == version
+	})
+}
+
+func (s *MemoryStore) ListKeys(ctx context.Context) ([]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]string, 0, len(s.keys))
+	for key := range s.keys {
+		res = append(res, key)
+	}
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) ListIndex(ctx context.Context) ([]*Record, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*Record, 0, len(s.records))
+	for _, r := range s.records {
+		aRecord := *r
+		aRecord.SpecJSON = ""
+		res = append(res, &aRecord)
+	}
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) ListModules(ctx context.Context, keyword string, limit int) ([]string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	dup := make(map[string]bool)
+	res := make([]string, 0, limit)
+	for _, r := range s.records {
+		if !dup[r.Module] && strings.Contains(strings.ToLower(r.Module), strings.ToLower(keyword)) {
+			dup[r.Module] = true
+			res = append(res, r.Module)
+		}
+	}
+	sort.Strings(res)
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) SearchSpecs(ctx context.Context, keyword string) ([]*Record, error) {
+	return s.filter(ctx, func(r *Record) bool {
+		return strings.Contains(r.SpecJSON, keyword)
+	})
+}
+
+func (s *MemoryStore) LastSync(ctx context.Context) (time.Time, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	var t time.Time
+	for _, l := range s.syncs {
+		if l.Status == SYNC_STATUS_COMPLETED && l.Time.After(t) {
+			t = l.Time
+		}
+	}
+	return t, ctx.Err()
+}
+
+func (s *MemoryStore) LastSyncLog(ctx context.Context) (*SyncLog, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if len(s.syncs) == 0 {
+		return nil, ctx.Err()
+	}
+	l := *s.syncs[len(s.syncs)-1]
+	return &l, ctx.Err()
+}
+
+func (s *MemoryStore) ListSyncLogs(ctx context.Context, limit int) ([]*SyncLog, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*SyncLog, 0, limit)
+	for idx := len(s.syncs) - 1; idx >= 0 && len(res) < limit; idx-- {
+		l := *s.syncs[idx]
+		res = append(res, &l)
+	}
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) GetSyncLog(ctx context.Context, syncID int64) (*SyncLog, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, l := range s.syncs {
+		if l.ID == syncID {
+			aLog := *l
+			return &aLog, ctx.Err()
+		}
+	}
+	return nil, ctx.Err()
+}
+
+func (s *MemoryStore) ListRepoStats(ctx context.Context, syncID int64) ([]*RepoStats, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*RepoStats, 0, 5)
+	for _, r := range s.stats {
+		if r.SyncID == syncID {
+			aStats := *r
+			res = append(res, &aStats)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Repo < res[j].Repo
+	})
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) ListChanges(ctx context.Context, syncID int64) ([]*Change, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*Change, 0, 100)
+	for _, c := range s.changes {
+		if c.SyncID == syncID {
+			aChange := *c
+			res = append(res, &aChange)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		a, b := res[i], res[j]
+		if a.Action != b.Action {
+			return a.Action < b.Action
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Module != b.Module {
+			return a.Module < b.Module
+		}
+		return a.Version < b.Version
+	})
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) ListCheckpoints(ctx context.Context, syncID int64) ([]*Checkpoint, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*Checkpoint, 0, 100)
+	for _, c := range s.checkpoints {
+		if c.SyncID == syncID {
+			aCheckpoint := *c
+			res = append(res, &aCheckpoint)
+		}
+	}
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) ListFailures(ctx context.Context) ([]*Failure, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*Failure, 0, len(s.failures))
+	for _, f := range s.failures {
+		aFailure := *f
+		res = append(res, &aFailure)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Repo != res[j].Repo {
+			return res[i].Repo < res[j].Repo
+		}
+		if res[i].Module != res[j].Module {
+			return res[i].Module < res[j].Module
+		}
+		return res[i].Version < res[j].Version
+	})
+	return res, ctx.Err()
+}
+
+func (s *MemoryStore) Begin(ctx context.Context) (Tx, error) {
+	if e := ctx.Err(); e != nil {
+		return nil, e
+	}
+	return &memoryTx{store: s, keys: make(map[string]bool), touched: make(map[string]time.Time), failures: make(map[string]*Failure), finished: make(map[int64]*SyncLog)}, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) GetSpecCache(ctx context.Context, hash string) (string, bool, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	json, ok := s.cache[hash]
+	return json, ok, ctx.Err()
+}
+
+func (s *MemoryStore) PutSpecCache(ctx context.Context, hash string, json string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.cache[hash] = json
+	return ctx.Err()
+}
+
+func (s *MemoryStore) CountSpecCache(ctx context.Context) (int, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.cache), ctx.Err()
+}
+
+func (s *MemoryStore) ClearSpecCache(ctx context.Context) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	n := len(s.cache)
+	s.cache = make(map[string]string)
+	return n, ctx.Err()
+}
+
+// 调用方持有写锁
+func (s *MemoryStore) delete(key string) {
+	if !s.keys[key] {
+		return
+	}
+	delete(s.keys, key)
+	for idx, r := range s.records {
+		if r.Key == key {
+			s.records = append(s.records[:idx], s.records[idx+1:]...)
+			return
+		}
+	}
+}
+
+// 调用方持有写锁，读操作返回的是副本，记录替换为新的副本而非原地修改
+func (s *MemoryStore) touch(touched map[string]time.Time) {
+	if len(touched) == 0 {
+		return
+	}
+	for idx, r := range s.records {
+		if t, ok := touched[r.Key]; ok {
+			aRecord := *r
+			aRecord.MTime = t
+			s.records[idx] = &aRecord
+		}
+	}
+}
+
+// 调用方持有写锁
+func (s *MemoryStore) replace(r *Record) {
+	if s.keys[r.Key] {
+		for idx, aRecord := range s.records {
+			if aRecord.Key == r.Key {
+				s.records[idx] = r
+				return
+			}
+		}
+	}
+	s.keys[r.Key] = true
+	s.records = append(s.records, r)
+}
+
+// 返回记录的副本，调用方修改不影响存储
+func (s *MemoryStore) filter(ctx context.Context, f func(r *Record) bool) ([]*Record, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	res := make([]*Record, 0, 2)
+	for _, r := range s.records {
+		if f(r) {
+			aRecord := *r
+			res = append(res, &aRecord)
+		}
+	}
+	return res, ctx.Err()
+}
+
+// ** Tx Impl **
+type memoryTx struct {
+	store    *MemoryStore
+	records  []*Record
+	keys     map[string]bool
+	replaced []*Record
+	touched  map[string]time.Time
+	// 值为nil表示删除
+	failures    map[string]*Failure
+	deleted     []string
+	syncs       []*SyncLog
+	finished    map[int64]*SyncLog
+	changes     []*Change
+	checkpoints []*Checkpoint
+	stats       []*RepoStats
+	done        bool
+}
+
+func (s *memoryTx) PutSpec(r *Record) error {
+	s.store.lock.RLock()
+	exist := s.store.keys[r.Key]
+	s.store.lock.RUnlock()
+	if exist || s.keys[r.Key] {
+		return ErrDuplicateKey
+	}
+	aRecord := *r
+	s.records = append(s.records, &aRecord)
+	s.keys[r.Key] = true
+	return nil
+}
+
+// 同步ID在开始时即分配，事务回滚时该ID不再使用
+func (s *memoryTx) StartSync(l *SyncLog) (int64, error) {
+	s.store.lock.Lock()
+	s.store.syncID++
+	id := s.store.syncID
+	s.store.lock.Unlock()
+	aLog := *l
+	aLog.ID, aLog.Status = id, SYNC_STATUS_RUNNING
+	s.syncs = append(s.syncs, &aLog)
+	return id, nil
+}
+
+func (s *memoryTx) FinishSync(l *SyncLog) error {
+	aLog := *l
+	s.finished[l.ID] = &aLog
+	return nil
+}
+
+func (s *memoryTx) RecordChange(c *Change) error {
+	aChange := *c
+	s.changes = append(s.changes, &aChange)
+	return nil
+}
+
+func (s *memoryTx) DeleteSpec(key string) error {
+	s.deleted = append(s.deleted, key)
+	s.failures[key] = nil
+	return nil
+}
+
+func (s *memoryTx) RecordCheckpoint(c *Checkpoint) error {
+	aCheckpoint := *c
+	s.checkpoints = append(s.checkpoints, &aCheckpoint)
+	return nil
+}
+
+func (s *memoryTx) ReplaceSpec(r *Record) error {
+	aRecord := *r
+	s.replaced = append(s.replaced, &aRecord)
+	return nil
+}
+
+func (s *memoryTx) TouchSpec(key string, t time.Time) error {
+	s.touched[key] = t
+	return nil
+}
+
+func (s *memoryTx) PutFailure(f *Failure) error {
+	aFailure := *f
+	s.failures[f.Key] = &aFailure
+	return nil
+}
+
+func (s *memoryTx) DeleteFailure(key string) error {
+	s.failures[key] = nil
+	return nil
+}
+
+func (s *memoryTx) RecordRepoStats(stats *RepoStats) error {
+	aStats := *stats
+	s.stats = append(s.stats, &aStats)
+	return nil
+}
+
+func (s *memoryTx) Commit() error {
+	if s.done {
+		return ErrTxDone
+	}
+	s.done = true
+	s.store.lock.Lock()
+	defer s.store.lock.Unlock()
+	for _, r := range s.records {
+		if s.store.keys[r.Key] {
+			continue
+		}
+		s.store.keys[r.Key] = true
+		s.store.records = append(s.store.records, r)
+	}
+	for _, r := range s.replaced {
+		s.store.replace(r)
+	}
+	s.store.touch(s.touched)
+	for key, f := range s.failures {
+		if f == nil {
+			delete(s.store.failures, key)
+		} else {
+			s.store.failures[key] = f
+		}
+	}
+	for _, key := range s.deleted {
+		s.store.delete(key)
+	}
+	s.store.syncs = append(s.store.syncs, s.syncs...)
+	for idx, l := range s.store.syncs {
+		if aLog, ok := s.finished[l.ID]; ok {
+			aLog.Time, aLog.Version, aLog.ConfigHash = l.Time, l.Version, l.ConfigHash
+			s.store.syncs[idx] = aLog
+		}
+	}
+	s.store.changes = append(s.store.changes, s.changes...)
+	for _, c := range s.checkpoints {
+		if s.store.checkpointKeys[*c] {
+			continue
+		}
+		s.store.checkpointKeys[*c] = true
+		s.store.checkpoints = append(s.store.checkpoints, c)
+	}
+	s.store.stats = append(s.store.stats, s.stats...)
+	return nil
+}
+
+func (s *memoryTx) Rollback() error {
+	if s.done {
+		return ErrTxDone
+	}
+	s.done = true
+	return nil
+}
@@ -0,0 +1,311 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// 内存与SQLite实现需表现一致，同一组用例分别在两种实现上执行
+// SQLite使用临时文件而非:memory:，:memory:在连接池的每个连接上是独立的数据库
+var _Backends = []struct {
+	name string
+	open func(t *testing.T) Store
+}{
+	{"memory", func(t *testing.T) Store {
+		return NewMemory()
+	}},
+	{"sqlite", func(t *testing.T) Store {
+		s, e := OpenSQLite(filepath.Join(t.TempDir(), "pandora.db"))
+		if e != nil {
+			t.Fatal(e)
+		}
+		return s
+	}},
+}
+
+func eachBackend(t *testing.T, f func(t *testing.T, s Store)) {
+	for _, b := range _Backends {
+		t.Run(b.name, func(t *testing.T) {
+			s := b.open(t)
+			defer s.Close()
+			f(t, s)
+		})
+	}
+}
+
+func testRecord(key string, module string, version string) *Record {
+	now := time.Now()
+	return &Record{Key: key, Repo: "r", Module: module, Version: version, Path: "r/" + module + "/" + version, SpecJSON: "{}", CTime: now, MTime: now}
+}
+
+func begin(t *testing.T, s Store) Tx {
+	tx, e := s.Begin(context.Background())
+	if e != nil {
+		t.Fatal(e)
+	}
+	return tx
+}
+
+func commit(t *testing.T, tx Tx) {
+	if e := tx.Commit(); e != nil {
+		t.Fatal(e)
+	}
+}
+
+func listIndex(t *testing.T, s Store) []*Record {
+	res, e := s.ListIndex(context.Background())
+	if e != nil {
+		t.Fatal(e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Key < res[j].Key
+	})
+	return res
+}
+
+func TestPutSpecDuplicate(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		tx := begin(t, s)
+		if e := tx.PutSpec(testRecord("a", "A", "1.0")); e != nil {
+			t.Fatal(e)
+		}
+		if e := tx.PutSpec(testRecord("a", "A", "1.0")); e != ErrDuplicateKey {
+			t.Fatalf("同一事务内重复写入: %v", e)
+		}
+		commit(t, tx)
+
+		tx = begin(t, s)
+		defer tx.Rollback()
+		if e := tx.PutSpec(testRecord("a", "A", "1.0")); e != ErrDuplicateKey {
+			t.Fatalf("写入已提交的Key: %v", e)
+		}
+		if e := tx.PutSpec(testRecord("b", "B", "1.0")); e != nil {
+			t.Fatal(e)
+		}
+	})
+}
+
+func TestRollback(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		tx := begin(t, s)
+		if e := tx.PutSpec(testRecord("a", "A", "1.0")); e != nil {
+			t.Fatal(e)
+		}
+		if e := tx.PutFailure(&Failure{Key: "b", Repo: "r", Module: "B", Version: "1.0", Category: "parse", Error: "boom", CTime: time.Now()}); e != nil {
+			t.Fatal(e)
+		}
+		id, e := tx.StartSync(&SyncLog{Time: time.Now(), Version: "test"})
+		if e != nil {
+			t.Fatal(e)
+		}
+		if e = tx.RecordChange(&Change{SyncID: id, Repo: "r", Module: "A", Version: "1.0", Action: CHANGE_ADDED}); e != nil {
+			t.Fatal(e)
+		}
+		if e = tx.Rollback(); e != nil {
+			t.Fatal(e)
+		}
+
+		if res := listIndex(t, s); len(res) != 0 {
+			t.Fatalf("回滚后仍有记录: %d", len(res))
+		}
+		if res, e := s.ListFailures(ctx); e != nil || len(res) != 0 {
+			t.Fatalf("回滚后仍有解析失败记录: %d %v", len(res), e)
+		}
+		if l, e := s.LastSyncLog(ctx); e != nil || l != nil {
+			t.Fatalf("回滚后仍有同步记录: %v %v", l, e)
+		}
+		if res, e := s.ListChanges(ctx, id); e != nil || len(res) != 0 {
+			t.Fatalf("回滚后仍有变化记录: %d %v", len(res), e)
+		}
+	})
+}
+
+func TestCommitDeleteReplace(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		ctx := context.Background()
+		tx := begin(t, s)
+		for _, r := range []*Record{testRecord("a", "A", "1.0"), testRecord("b", "B", "1.0")} {
+			if e := tx.PutSpec(r); e != nil {
+				t.Fatal(e)
+			}
+		}
+		if e := tx.PutFailure(&Failure{Key: "b", Repo: "r", Module: "B", Version: "1.0", Category: "parse", Error: "boom", CTime: time.Now()}); e != nil {
+			t.Fatal(e)
+		}
+		commit(t, tx)
+
+		tx = begin(t, s)
+		changed := testRecord("a", "A", "1.0")
+		changed.Path = "r/A/1.0/A.podspec.json"
+		for _, r := range []*Record{changed, testRecord("c", "C", "2.0")} {
+			if e := tx.ReplaceSpec(r); e != nil {
+				t.Fatal(e)
+			}
+		}
+		if e := tx.DeleteSpec("b"); e != nil {
+			t.Fatal(e)
+		}
+		commit(t, tx)
+
+		res := listIndex(t, s)
+		if len(res) != 2 || res[0].Key != "a" || res[1].Key != "c" {
+			t.Fatalf("提交后的记录不符: %v", res)
+		}
+		if res[0].Path != changed.Path {
+			t.Fatalf("ReplaceSpec未覆盖已有记录: %s", res[0].Path)
+		}
+		if failures, e := s.ListFailures(ctx); e != nil || len(failures) != 0 {
+			t.Fatalf("DeleteSpec未删除解析失败记录: %d %v", len(failures), e)
+		}
+	})
+}
+
+func TestTxDone(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		tx := begin(t, s)
+		commit(t, tx)
+		if e := tx.Commit(); e != ErrTxDone {
+			t.Fatalf("重复提交: %v", e)
+		}
+		if e := tx.Rollback(); e != ErrTxDone {
+			t.Fatalf("提交后回滚: %v", e)
+		}
+	})
+}
+
+func TestListChangesOrder(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		tx := begin(t, s)
+		id, e := tx.StartSync(&SyncLog{Time: time.Now(), Version: "test"})
+		if e != nil {
+			t.Fatal(e)
+		}
+		changes := []*Change{
+			{SyncID: id, Repo: "r2", Module: "A", Version: "1.0", Action: CHANGE_REMOVED},
+			{SyncID: id, Repo: "r1", Module: "B", Version: "2.0", Action: CHANGE_ADDED},
+			{SyncID: id, Repo: "r1", Module: "B", Version: "1.0", Action: CHANGE_ADDED},
+			{SyncID: id, Repo: "r2", Module: "A", Version: "1.0", Action: CHANGE_ADDED},
+			{SyncID: id, Repo: "r1", Module: "A", Version: "1.0", Action: CHANGE_CHANGED},
+			{SyncID: id + 1, Repo: "r1", Module: "Z", Version: "1.0", Action: CHANGE_ADDED},
+		}
+		for _, c := range changes {
+			if e = tx.RecordChange(c); e != nil {
+				t.Fatal(e)
+			}
+		}
+		commit(t, tx)
+
+		res, e := s.ListChanges(context.Background(), id)
+		if e != nil {
+			t.Fatal(e)
+		}
+		expect := []string{
+			"added r1 B 1.0",
+			"added r1 B 2.0",
+			"added r2 A 1.0",
+			"changed r1 A 1.0",
+			"removed r2 A 1.0",
+		}
+		if len(res) != len(expect) {
+			t.Fatalf("变化数量不符: %d", len(res))
+		}
+		for idx, c := range res {
+			if aChange := c.Action + " " + c.Repo + " " + c.Module + " " + c.Version; aChange != expect[idx] {
+				t.Fatalf("第%d条变化为 %s，应为 %s", idx, aChange, expect[idx])
+			}
+		}
+	})
+}
+
+func TestRecordCheckpointDuplicate(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		tx := begin(t, s)
+		id, e := tx.StartSync(&SyncLog{Time: time.Now(), Version: "test"})
+		if e != nil {
+			t.Fatal(e)
+		}
+		for _, c := range []*Checkpoint{{id, "r", "A"}, {id, "r", "A"}, {id, "r", "B"}} {
+			if e = tx.RecordCheckpoint(c); e != nil {
+				t.Fatal(e)
+			}
+		}
+		commit(t, tx)
+
+		// 已提交的检查点再次记录时忽略
+		tx = begin(t, s)
+		for _, c := range []*Checkpoint{{id, "r", "B"}, {id, "r2", "A"}} {
+			if e = tx.RecordCheckpoint(c); e != nil {
+				t.Fatal(e)
+			}
+		}
+		commit(t, tx)
+
+		res, e := s.ListCheckpoints(context.Background(), id)
+		if e != nil {
+			t.Fatal(e)
+		}
+		m := make(map[string]int)
+		for _, c := range res {
+			m[c.Repo+"/"+c.Module]++
+		}
+		if len(res) != 3 || m["r/A"] != 1 || m["r/B"] != 1 || m["r2/A"] != 1 {
+			t.Fatalf("检查点为 %v", m)
+		}
+	})
+}
+
+func TestTouchSpec(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		tx := begin(t, s)
+		if e := tx.PutSpec(testRecord("a", "A", "1.0")); e != nil {
+			t.Fatal(e)
+		}
+		commit(t, tx)
+
+		touched := time.Now().Add(time.Hour).Truncate(time.Second)
+		tx = begin(t, s)
+		for _, key := range []string{"a", "missing"} {
+			if e := tx.TouchSpec(key, touched); e != nil {
+				t.Fatal(e)
+			}
+		}
+		commit(t, tx)
+
+		res := listIndex(t, s)
+		if len(res) != 1 || !res[0].MTime.Equal(touched) {
+			t.Fatalf("TouchSpec后的记录: %v", res)
+		}
+		if specs, e := s.GetSpecs(context.Background(), "A", "1.0"); e != nil || len(specs) != 1 || specs[0].SpecJSON != "{}" {
+			t.Fatalf("TouchSpec不应修改spec: %v %v", specs, e)
+		}
+	})
+}
+
+func TestListVersionsWithoutSpec(t *testing.T) {
+	eachBackend(t, func(t *testing.T, s Store) {
+		noSpec := testRecord("b", "A", "2.0")
+		noSpec.SpecJSON = ""
+		tx := begin(t, s)
+		for _, r := range []*Record{testRecord("a", "A", "1.0"), noSpec} {
+			if e := tx.PutSpec(r); e != nil {
+				t.Fatal(e)
+			}
+		}
+		commit(t, tx)
+
+		res, e := s.ListVersions(context.Background(), "A")
+		if e != nil {
+			t.Fatal(e)
+		}
+		if len(res) != 1 || res[0].Version != "1.0" {
+			t.Fatalf("没有spec的版本不应返回: %v", res)
+		}
+		if all := listIndex(t, s); len(all) != 2 {
+			t.Fatalf("没有spec的版本仍应入库: %d", len(all))
+		}
+	})
+}
@@ -0,0 +1,427 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const (
+	__STR_DB_UNQ_ERR     = `UNIQUE constraint failed:`
+	__STR_DB_DUP_COL_ERR = `duplicate column name:`
+)
+
+// 基于SQLite文件的存储
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// 打开dbPath指定的SQLite数据库，不存在时创建
+func OpenSQLite(dbPath string) (*SQLiteStore, error) {
+	// 索引时多个线程会并发写入缓存，等待锁释放而非直接返回database is locked
+	db, e := sql.Open("sqlite3", dbPath+"?_busy_timeout=5000")
+	if e != nil {
+		return nil, e
+	}
+	for _, stmt := range []string{_SQL_REPO_TB_CREATE, _SQL_SPEC_FAILURE_TB_CREATE, _SQL_REPO_SYNCLOG_TB_CREATE, _SQL_SYNC_CHANGE_TB_CREATE, _SQL_SYNC_CHANGE_INDEX_CREATE, _SQL_SYNC_CHECKPOINT_TB_CREATE, _SQL_REPO_STATS_TB_CREATE, _SQL_SPEC_CACHE_TB_CREATE} {
+		if _, e = db.Exec(stmt); e != nil {
+			db.Close()
+			return nil, e
+		}
+	}
+	for _, stmt := range _SQL_MIGRATIONS {
+		if _, e = db.Exec(stmt); e != nil && !strings.HasPrefix(e.Error(), __STR_DB_DUP_COL_ERR) {
+			db.Close()
+			return nil, e
+		}
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) ListVersions(ctx context.Context, module string) ([]*Record, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_VERSIONS, module)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*Record, 0, 10)
+	for rows.Next() {
+		r := &Record{Module: module}
+		if e = rows.Scan(&r.Repo, &r.Version); e != nil {
+			continue
+		}
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) GetSpecs(ctx context.Context, module string, version string) ([]*Record, error) {
+	return s.queryRecords(ctx, _SQL_QUERY_SPEC, module, version)
+}
+
+func (s *SQLiteStore) ListKeys(ctx context.Context) ([]string, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_EXIST_KEY)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]string, 0, 1000)
+	for rows.Next() {
+		var key string
+		if e = rows.Scan(&key); e != nil {
+			return nil, e
+		}
+		if len(key) > 0 {
+			res = append(res, key)
+		}
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) ListIndex(ctx context.Context) ([]*Record, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_INDEX)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*Record, 0, 1000)
+	for rows.Next() {
+		r := new(Record)
+		var ctime, mtime sql.NullTime
+		if e = rows.Scan(&r.Key, &r.Repo, &r.Module, &r.Version, &r.Path, &ctime, &mtime); e != nil {
+			return nil, e
+		}
+		r.CTime, r.MTime = ctime.Time, mtime.Time
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) ListModules(ctx context.Context, keyword string, limit int) ([]string, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_MODULES, "%"+keyword+"%", limit)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]string, 0, limit)
+	for rows.Next() {
+		var m string
+		if e = rows.Scan(&m); e == nil {
+			res = append(res, m)
+		}
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) SearchSpecs(ctx context.Context, keyword string) ([]*Record, error) {
+	return s.queryRecords(ctx, _SQL_QUERY_SEARCH_SPEC, "%"+keyword+"%")
+}
+
+func (s *SQLiteStore) LastSync(ctx context.Context) (time.Time, error) {
+	var t time.Time
+	e := s.db.QueryRowContext(ctx, _SQL_QUERY_LAST_SYNC, SYNC_STATUS_COMPLETED).Scan(&t)
+	if e == sql.ErrNoRows {
+		return t, nil
+	}
+	return t, e
+}
+
+func (s *SQLiteStore) LastSyncLog(ctx context.Context) (*SyncLog, error) {
+	return s.querySyncLog(ctx, _SQL_QUERY_LAST_SYNC_LOG)
+}
+
+func (s *SQLiteStore) GetSyncLog(ctx context.Context, syncID int64) (*SyncLog, error) {
+	return s.querySyncLog(ctx, _SQL_QUERY_SYNC_LOG, syncID)
+}
+
+func (s *SQLiteStore) ListSyncLogs(ctx context.Context, limit int) ([]*SyncLog, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_SYNC_LOGS, limit)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*SyncLog, 0, limit)
+	for rows.Next() {
+		l, e := scanSyncLog(rows)
+		if e != nil {
+			return nil, e
+		}
+		res = append(res, l)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) ListRepoStats(ctx context.Context, syncID int64) ([]*RepoStats, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_REPO_STATS, syncID)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*RepoStats, 0, 5)
+	for rows.Next() {
+		r := new(RepoStats)
+		var t sql.NullTime
+		if e = rows.Scan(&r.SyncID, &t, &r.Repo, &r.Success, &r.MissingSpec, &r.ParseFailure, &r.EvaluatorFailure, &r.DuplicateKey, &r.GitHead); e != nil {
+			return nil, e
+		}
+		r.SyncTime = t.Time
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) ListChanges(ctx context.Context, syncID int64) ([]*Change, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_CHANGES, syncID)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*Change, 0, 100)
+	for rows.Next() {
+		c := new(Change)
+		if e = rows.Scan(&c.SyncID, &c.Repo, &c.Module, &c.Version, &c.Action); e != nil {
+			return nil, e
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) ListCheckpoints(ctx context.Context, syncID int64) ([]*Checkpoint, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_CHECKPOINTS, syncID)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*Checkpoint, 0, 100)
+	for rows.Next() {
+		c := new(Checkpoint)
+		if e = rows.Scan(&c.SyncID, &c.Repo, &c.Module); e != nil {
+			return nil, e
+		}
+		res = append(res, c)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) ListFailures(ctx context.Context) ([]*Failure, error) {
+	rows, e := s.db.QueryContext(ctx, _SQL_QUERY_FAILURES)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*Failure, 0, 10)
+	for rows.Next() {
+		f := new(Failure)
+		var ctime sql.NullTime
+		if e = rows.Scan(&f.Key, &f.Repo, &f.Module, &f.Version, &f.Path, &f.Category, &f.Error, &ctime); e != nil {
+			return nil, e
+		}
+		f.CTime = ctime.Time
+		res = append(res, f)
+	}
+	return res, rows.Err()
+}
+
+func (s *SQLiteStore) Begin(ctx context.Context) (Tx, error) {
+	tx, e := s.db.BeginTx(ctx, nil)
+	if e != nil {
+		return nil, e
+	}
+	repoStmt, e := tx.Prepare(_SQL_INSERT_REPO)
+	if e != nil {
+		tx.Rollback()
+		return nil, e
+	}
+	checkpointStmt, e := tx.Prepare(_SQL_INSERT_CHECKPOINT)
+	if e != nil {
+		repoStmt.Close()
+		tx.Rollback()
+		return nil, e
+	}
+	changeStmt, e := tx.Prepare(_SQL_INSERT_CHANGE)
+	if e != nil {
+		repoStmt.Close()
+		checkpointStmt.Close()
+		tx.Rollback()
+		return nil, e
+	}
+	return &sqliteTx{tx: tx, repoStmt: repoStmt, checkpointStmt: checkpointStmt, changeStmt: changeStmt}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) GetSpecCache(ctx context.Context, hash string) (string, bool, error) {
+	var json string
+	e := s.db.QueryRowContext(ctx, _SQL_QUERY_SPEC_CACHE, hash).Scan(&json)
+	if e == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if e != nil {
+		return "", false, e
+	}
+	return json, true, nil
+}
+
+func (s *SQLiteStore) PutSpecCache(ctx context.Context, hash string, json string) error {
+	_, e := s.db.ExecContext(ctx, _SQL_INSERT_SPEC_CACHE, hash, json, time.Now())
+	return e
+}
+
+func (s *SQLiteStore) CountSpecCache(ctx context.Context) (int, error) {
+	var n int
+	e := s.db.QueryRowContext(ctx, _SQL_COUNT_SPEC_CACHE).Scan(&n)
+	return n, e
+}
+
+func (s *SQLiteStore) ClearSpecCache(ctx context.Context) (int, error) {
+	res, e := s.db.ExecContext(ctx, _SQL_CLEAR_SPEC_CACHE)
+	if e != nil {
+		return 0, e
+	}
+	n, e := res.RowsAffected()
+	return int(n), e
+}
+
+func (s *SQLiteStore) querySyncLog(ctx context.Context, query string, args ...interface{}) (*SyncLog, error) {
+	l, e := scanSyncLog(s.db.QueryRowContext(ctx, query, args...))
+	if e == sql.ErrNoRows {
+		return nil, nil
+	}
+	return l, e
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSyncLog(row rowScanner) (*SyncLog, error) {
+	l := new(SyncLog)
+	var ms int64
+	e := row.Scan(&l.ID, &l.Time, &l.Status, &ms, &l.Added, &l.Changed, &l.Removed, &l.Failed, &l.Version, &l.ConfigHash)
+	if e != nil {
+		return nil, e
+	}
+	l.Duration = time.Duration(ms) * time.Millisecond
+	return l, nil
+}
+
+func (s *SQLiteStore) queryRecords(ctx context.Context, query string, args ...interface{}) ([]*Record, error) {
+	rows, e := s.db.QueryContext(ctx, query, args...)
+	if e != nil {
+		return nil, e
+	}
+	defer rows.Close()
+	res := make([]*Record, 0, 2)
+	for rows.Next() {
+		r := new(Record)
+		var json sql.NullString
+		var ctime sql.NullTime
+		if e = rows.Scan(&r.Key, &r.Repo, &r.Module, &r.Version, &r.Path, &json, &ctime); e != nil {
+			continue
+		}
+		r.SpecJSON, r.CTime = json.String, ctime.Time
+		res = append(res, r)
+	}
+	return res, rows.Err()
+}
+
+// ** Tx Impl **
+type sqliteTx struct {
+	tx             *sql.Tx
+	repoStmt       *sql.Stmt
+	checkpointStmt *sql.Stmt
+	changeStmt     *sql.Stmt
+}
+
+func (s *sqliteTx) PutSpec(r *Record) error {
+	_, e := s.repoStmt.Exec(r.Key, r.Repo, r.Module, r.Version, r.Path, r.SpecJSON, r.CTime, r.MTime)
+	if e != nil && strings.HasPrefix(e.Error(), __STR_DB_UNQ_ERR) {
+		return ErrDuplicateKey
+	}
+	return e
+}
+
+func (s *sqliteTx) ReplaceSpec(r *Record) error {
+	_, e := s.tx.Exec(_SQL_REPLACE_REPO, r.Key, r.Repo, r.Module, r.Version, r.Path, r.SpecJSON, r.CTime, r.MTime)
+	return e
+}
+
+func (s *sqliteTx) TouchSpec(key string, t time.Time) error {
+	_, e := s.tx.Exec(_SQL_TOUCH_REPO, t, key)
+	return e
+}
+
+func (s *sqliteTx) DeleteSpec(key string) error {
+	if _, e := s.tx.Exec(_SQL_DELETE_REPO, key); e != nil {
+		return e
+	}
+	return s.DeleteFailure(key)
+}
+
+func (s *sqliteTx) PutFailure(f *Failure) error {
+	_, e := s.tx.Exec(_SQL_INSERT_FAILURE, f.Key, f.Repo, f.Module, f.Version, f.Path, f.Category, f.Error, f.CTime)
+	return e
+}
+
+func (s *sqliteTx) DeleteFailure(key string) error {
+	_, e := s.tx.Exec(_SQL_DELETE_FAILURE, key)
+	return e
+}
+
+func (s *sqliteTx) StartSync(l *SyncLog) (int64, error) {
+	res, e := s.tx.Exec(_SQLINSERT_LOG, l.Time, SYNC_STATUS_RUNNING, l.Version, l.ConfigHash)
+	if e != nil {
+		return 0, e
+	}
+	return res.LastInsertId()
+}
+
+func (s *sqliteTx) FinishSync(l *SyncLog) error {
+	_, e := s.tx.Exec(_SQL_UPDATE_LOG, l.Status, l.Duration.Milliseconds(), l.Added, l.Changed, l.Removed, l.Failed, l.ID)
+	return e
+}
+
+func (s *sqliteTx) RecordChange(c *Change) error {
+	_, e := s.changeStmt.Exec(c.SyncID, c.Repo, c.Module, c.Version, c.Action)
+	return e
+}
+
+func (s *sqliteTx) RecordCheckpoint(c *Checkpoint) error {
+	_, e := s.checkpointStmt.Exec(c.SyncID, c.Repo, c.Module)
+	return e
+}
+
+func (s *sqliteTx) RecordRepoStats(stats *RepoStats) error {
+	_, e := s.tx.Exec(_SQL_INSERT_REPO_STATS, stats.SyncID, stats.SyncTime, stats.Repo, stats.Success, stats.MissingSpec, stats.ParseFailure, stats.EvaluatorFailure, stats.DuplicateKey, stats.GitHead)
+	return e
+}
+
+func (s *sqliteTx) Commit() error {
+	s.close()
+	return txDone(s.tx.Commit())
+}
+
+func (s *sqliteTx) Rollback() error {
+	s.close()
+	return txDone(s.tx.Rollback())
+}
+
+// 与内存实现一致，重复提交或回滚时返回ErrTxDone
+func txDone(e error) error {
+	if e == sql.ErrTxDone {
+		return ErrTxDone
+	}
+	return e
+}
+
+func (s *sqliteTx) close() {
+	s.repoStmt.Close()
+	s.checkpointStmt.Close()
+	s.changeStmt.Close()
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrDuplicateKey = errors.New("重复主键")
+	ErrTxDone       = errors.New("事务已提交或回滚")
+)
+
+// 索引中的一条模块版本记录，Key为版本目录的MD5
+type Record struct {
+	Key      string
+	Repo     string
+	Module   string
+	Version  string
+	Path     string
+	SpecJSON string
+	// 首次入库的时间
+	CTime time.Time
+	// 最近一次写入的时间，早于该字段加入时入库的记录为零值
+	MTime time.Time
+}
+
+// 解析失败的spec，Key与Record.Key相同，Category为索引失败的分类
+type Failure struct {
+	Key      string
+	Repo     string
+	Module   string
+	Version  string
+	Path     string
+	Category string
+	Error    string
+	CTime    time.Time
+}
+
+// 同步状态
+const (
+	SYNC_STATUS_RUNNING     = "running"
+	SYNC_STATUS_COMPLETED   = "completed"
+	SYNC_STATUS_INTERRUPTED = "interrupted"
+)
+
+// 同步中模块版本的变化
+const (
+	CHANGE_ADDED   = "added"
+	CHANGE_CHANGED = "changed"
+	CHANGE_REMOVED = "removed"
+)
+
+// updatelog中的一次同步，进程异常退出时Status保持为running
+// Duration为累计耗时，继续中断的同步时不含中断期间；Version为执行同步的pandora版本
+type SyncLog struct {
+	ID         int64
+	Time       time.Time
+	Status     string
+	Duration   time.Duration
+	Added      int
+	Changed    int
+	Removed    int
+	Failed     int
+	Version    string
+	ConfigHash string
+}
+
+// 一次同步中出现、变化或消失的模块版本
+type Change struct {
+	SyncID  int64
+	Repo    string
+	Module  string
+	Version string
+	Action  string
+}
+
+// 同步检查点，表示模块在该次同步中需要处理的版本均已提交
+type Checkpoint struct {
+	SyncID int64
+	Repo   string
+	Module string
+}
+
+// 一次同步中某仓库的索引统计
+type RepoStats struct {
+	SyncID           int64
+	SyncTime         time.Time
+	Repo             string
+	Success          int
+	MissingSpec      int
+	ParseFailure     int
+	EvaluatorFailure int
+	DuplicateKey     int
+	// 同步时仓库的git HEAD，非git仓库为空
+	GitHead string
+}
+
+// 索引存储，读操作并发安全；写操作通过Begin开启的事务完成
+type Store interface {
+	// 模块(基础模块名)在各仓库中有spec的版本，仅填充Repo、Module、Version
+	// 解析失败及CDN仓库中未下载spec的版本依赖未知，不参与版本解析
+	ListVersions(ctx context.Context, module string) ([]*Record, error)
+	// 模块某版本在各仓库中的记录
+	GetSpecs(ctx context.Context, module string, version string) ([]*Record, error)
+	// 已索引的全部Key
+	ListKeys(ctx context.Context) ([]string, error)
+	// 已索引的全部记录，不含SpecJSON
+	ListIndex(ctx context.Context) ([]*Record, error)
+	// 名称包含keyword的模块，按名称排序
+	ListModules(ctx context.Context, keyword string, limit int) ([]string, error)
+	// spec JSON中包含keyword的记录
+	SearchSpecs(ctx context.Context, keyword string) ([]*Record, error)
+	// 最后一次完成的同步的时间，从未完成同步时返回零值
+	LastSync(ctx context.Context) (time.Time, error)
+	// 最近一次同步(含未完成的)，从未同步时返回nil
+	LastSyncLog(ctx context.Context) (*SyncLog, error)
+	// 最近的limit次同步，按时间从新到旧排序
+	ListSyncLogs(ctx context.Context, limit int) ([]*SyncLog, error)
+	// 指定ID的同步，不存在时返回nil
+	GetSyncLog(ctx context.Context, syncID int64) (*SyncLog, error)
+	// 某次同步中各仓库的索引统计
+	ListRepoStats(ctx context.Context, syncID int64) ([]*RepoStats, error)
+	// 某次同步中模块版本的变化，按Action、仓库、模块、版本排序
+	ListChanges(ctx context.Context, syncID int64) ([]*Change, error)
+	// 某次同步已记录的检查点
+	ListCheckpoints(ctx context.Context, syncID int64) ([]*Checkpoint, error)
+	// 全部解析失败的spec，按仓库、模块、版本排序
+	ListFailures(ctx context.Context) ([]*Failure, error)
+	Begin(ctx context.Context) (Tx, error)
+	Close() error
+
+	// podspec求值缓存，hash为podspec文件内容的哈希
+	GetSpecCache(ctx context.Context, hash string) (string, bool, error)
+	PutSpecCache(ctx context.Context, hash string, json string) error
+	CountSpecCache(ctx context.Context) (int, error)
+	// 清空缓存，返回清除的条数
+	ClearSpecCache(ctx context.Context) (int, error)
+}
+
+// 写事务，Commit前的写入对读操作不可见
+type Tx interface {
+	// 写入一条记录，Key已存在时返回ErrDuplicateKey
+	PutSpec(r *Record) error
+	// 写入一条记录，Key已存在时覆盖
+	ReplaceSpec(r *Record) error
+	// 更新记录的MTime，spec文件修改但内容未变化时使用，不存在时忽略
+	TouchSpec(key string, t time.Time) error
+	// 删除记录及其解析失败记录，不存在时忽略
+	DeleteSpec(key string) error
+	// 记录解析失败，Key已存在时覆盖
+	PutFailure(f *Failure) error
+	// 删除解析失败记录，不存在时忽略
+	DeleteFailure(key string) error
+	// 记录一次开始的同步，状态为running，返回同步ID
+	StartSync(l *SyncLog) (int64, error)
+	// 按l.ID更新同步的状态、耗时及各项计数
+	FinishSync(l *SyncLog) error
+	// 记录模块版本的变化
+	RecordChange(c *Change) error
+	// 记录同步检查点，已存在时忽略
+	RecordCheckpoint(c *Checkpoint) error
+	// 记录一次同步中某仓库的索引统计
+	RecordRepoStats(stats *RepoStats) error
+	// 事务已提交或回滚时返回ErrTxDone
+	Commit() error
+	Rollback() error
+}
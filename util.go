@@ -31,6 +31,15 @@ func exitWithMessage(msg string, p bool) {
 	os.Exit(1)
 }
 
+// json输出模式下以JSON输出错误信息后退出，否则同exitWithMessage
+func exitWithOutputError(msg string, p bool) {
+	if isJSONOutput() {
+		printJSONError(msg)
+		os.Exit(1)
+	}
+	exitWithMessage(msg, p)
+}
+
 func ParseBinaryString(s string) (int, error) {
 	s = strings.TrimSpace(s)
 	if !regexp.MustCompile(`^[0-1]+$`).MatchString(s) {
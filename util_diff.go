@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+const __DIFF_CONTEXT = 3
+
+type diffOp struct {
+	Kind byte // ' ', '-', '+'
+	Line string
+	A    int
+	B    int
+}
+
+// 生成统一格式(unified)的差异文本，无差异时返回空字符串
+func UnifiedDiff(nameA string, a []byte, nameB string, b []byte) string {
+	linesA := splitLines(a)
+	linesB := splitLines(b)
+	ops := diffLines(linesA, linesB)
+	changed := false
+	for _, op := range ops {
+		if op.Kind != ' ' {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return ""
+	}
+
+	var buffer bytes.Buffer
+	buffer.WriteString("--- " + nameA + "\n")
+	buffer.WriteString("+++ " + nameB + "\n")
+	l := len(ops)
+	for i := 0; i < l; {
+		if ops[i].Kind == ' ' {
+			i++
+			continue
+		}
+		start := i - __DIFF_CONTEXT
+		if start < 0 {
+			start = 0
+		}
+		end := i
+		for end < l {
+			if ops[end].Kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < l && ops[next].Kind == ' ' {
+				next++
+			}
+			if next == l || next-end > __DIFF_CONTEXT*2 {
+				break
+			}
+			end = next
+		}
+		end += __DIFF_CONTEXT
+		if end > l {
+			end = l
+		}
+		writeHunk(&buffer, ops[start:end])
+		i = end
+	}
+	return buffer.String()
+}
+
+func writeHunk(buffer *bytes.Buffer, ops []diffOp) {
+	var countA, countB int
+	for _, op := range ops {
+		if op.Kind != '+' {
+			countA++
+		}
+		if op.Kind != '-' {
+			countB++
+		}
+	}
+	buffer.WriteString("@@ -" + hunkRange(ops[0].A, countA) + " +" + hunkRange(ops[0].B, countB) + " @@\n")
+	for _, op := range ops {
+		buffer.WriteByte(op.Kind)
+		buffer.WriteString(op.Line)
+		buffer.WriteString("\n")
+	}
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return strconv.Itoa(start) + ",0"
+	}
+	return strconv.Itoa(start+1) + "," + strconv.Itoa(count)
+}
+
+func splitLines(b []byte) []string {
+	if len(b) == 0 {
+		return nil
+	}
+	s := strings.TrimSuffix(string(b), "\n")
+	return strings.Split(s, "\n")
+}
+
+// 基于最长公共子序列的行级差异
+func diffLines(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	res := make([]diffOp, 0, n+m)
+	i, j := 0, 0
+	for i < n && j < m {
+		if a[i] == b[j] {
+			res = append(res, diffOp{Kind: ' ', Line: a[i], A: i, B: j})
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			res = append(res, diffOp{Kind: '-', Line: a[i], A: i, B: j})
+			i++
+		} else {
+			res = append(res, diffOp{Kind: '+', Line: b[j], A: i, B: j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		res = append(res, diffOp{Kind: '-', Line: a[i], A: i, B: j})
+	}
+	for ; j < m; j++ {
+		res = append(res, diffOp{Kind: '+', Line: b[j], A: i, B: j})
+	}
+	return res
+}
+
+func printDiff(diff string) {
+	for _, line := range splitLines([]byte(diff)) {
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			println(line)
+		case strings.HasPrefix(line, "+"):
+			printGreen(line, false)
+		case strings.HasPrefix(line, "-"):
+			printRed(line, false)
+		default:
+			println(line)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"os"
 
 	cp "github.com/fatih/color"
 )
@@ -53,14 +54,55 @@ func printlnDebug(s ...interface{}) {
 		tmp := make([]interface{}, 1, len(s)+1)
 		tmp[0] = "DEBUG =>"
 		tmp = append(tmp, s...)
-		fmt.Println(tmp...)
+		if isJSONOutput() {
+			fmt.Fprintln(os.Stderr, tmp...)
+		} else {
+			fmt.Println(tmp...)
+		}
 	}
 }
 
 const __help_info = `
 ****** 参数帮助 ******
+--format json|text :输出格式，json模式下结果输出到stdout，日志输出到stderr
+--memory         :使用内存存储，执行命令前临时索引本地Pod仓库，不读写pandora.db
+-cache [clear]   :查看Spec求值缓存条数，clear清空缓存
 --sync           :索引本地Pod并同步到数据库，建议先执行pod repo update命令更新本地Pod仓库
+    --resume        :继续最近一次中断的同步，跳过已完成的模块
+    --retry-failed  :仅重新解析之前解析失败的spec
+-failures        :按错误信息分组列出解析失败的spec
+-log [ID]        :列出最近的同步记录 [--limit 20]，指定ID时查看该次同步的仓库统计及出现/变化/消失的版本
+-news            :列出新入库的模块版本并标出主版本升级，例如: pandora -news --since 2024-01-01 --podfile Podfile
+    --since 日期     :列出该时间之后入库的版本，格式 2006-01-02 或 2006-01-02 15:04:05
+    --last-sync     :列出最近一次同步入库的版本(默认)
+    --podfile 路径   :仅列出Podfile直接及间接依赖的模块，可指定多个
 --dep            :查询某版本的模块所有依赖，例如: pandora --dep NVNetwork 1.0.3
+-up              :分析Podfile依赖并计算升级结果，例如: pandora -up Podfile [--flag 目标Podfile] [--out_type 11]
+    --apply [目录]   :将升级结果写回Podfile(先输出diff预览)，指定目录时写入副本，原地改写需配合--yes
+    --yes           :配合--apply，确认原地改写Podfile
+    --implicit      :配合--apply，将隐性依赖追加到Podfile
+    --dry_run       :配合--apply，仅输出diff预览
+    --targets       :按target分别输出依赖图及target间的版本差异
+    --target_diff A B :输出两个target之间不同的模块及版本
+    --dot           :输出Graphviz DOT格式的依赖图
+    --mermaid       :输出Mermaid格式的依赖图
+    --plantuml      :输出PlantUML格式的依赖图
+-graph           :输出模块某版本的传递依赖图，例如: pandora -graph NVNetwork 1.0.3 [--out deps.dot]
+    --type dot|mermaid|plantuml :依赖图格式，默认dot
+-serve           :启动只读HTTP查询服务，例如: pandora -serve --addr 127.0.0.1:8080
+    --addr          :监听地址，默认127.0.0.1:8080
+    --allow_sync    :允许通过POST /api/sync触发同步
+    --token         :POST /api/sync需携带 Authorization: Bearer <token>
+    GET  /api/search?q=&limit=            :模糊查询模块
+    GET  /api/versions?module=&constraint= :查询模块版本
+    GET  /api/newest?module=&constraint=   :查询满足约束的最新版本
+    GET  /api/dependencies?module=&version= :查询模块依赖
+    GET  /api/dependents?module=&version=  :查询依赖该模块的模块
+    GET  /api/spec?module=&version=        :查询spec JSON
+    GET|POST /api/sync                      :查询同步状态|触发同步
+--collapse       :配合依赖图输出，将子模块合并到基础模块
+--depth N        :配合依赖图输出，仅输出N层以内的依赖
+--max_nodes N    :配合依赖图输出，最多输出N个模块，Mermaid和PlantUML默认150
 
 详情请参考：
 **********************